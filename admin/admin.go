@@ -0,0 +1,295 @@
+// Package admin provides an HTTP API (JSON) to operate a live runner: status, scaling,
+// listing and canceling jobs, stopping and draining.
+//
+// Routes, relative to where the handler is mounted:
+//
+//	GET    /status       runner status
+//	POST   /scale        set concurrency: {"concurrency": 10}, 409 if the runner is not running
+//	GET    /jobs         list jobs, optionally by state: /jobs?state=running&state=queued
+//	GET    /jobs/{id}    job state
+//	DELETE /jobs/{id}    cancel job
+//	POST   /stop         stop runner, canceling running jobs
+//	POST   /drain        stop accepting new jobs, letting the others finish
+//
+// To mount under a prefix, use http.StripPrefix:
+//
+//	mux.Handle("/admin/", http.StripPrefix("/admin", admin.New(r, admin.Config{})))
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+)
+
+// Config allows setup of the handler.
+type Config struct {
+	// Authorize is called before every request. If it returns an error, the request is rejected
+	// as unauthorized with the error as message. Nil allows all requests.
+	Authorize func(*http.Request) error
+}
+
+// Status is the runner status.
+type Status struct {
+	Concurrency   int            `json:"concurrency"`
+	RunningJobs   int            `json:"running_jobs"`
+	PendingJobs   map[string]int `json:"pending_jobs"`
+	DelayedJobs   int            `json:"delayed_jobs"`
+	ThrottledJobs int            `json:"throttled_jobs"`
+	LeakedJobs    int            `json:"leaked_jobs"`
+	DroppedEvents uint64         `json:"dropped_events"`
+	Draining      bool           `json:"draining"`
+}
+
+// Job is the state of a job.
+type Job struct {
+	ID             job.ID     `json:"id"`
+	IdempotencyKey string     `json:"idempotency_key,omitempty"`
+	State          string     `json:"state"`
+	Priority       int        `json:"priority"`
+	EnqueuedAt     time.Time  `json:"enqueued_at"`
+	ScheduledAt    *time.Time `json:"scheduled_at,omitempty"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	Attempts       int        `json:"attempts"`
+}
+
+// Scale is the request to set the concurrency of the runner.
+type Scale struct {
+	Concurrency *int `json:"concurrency"`
+}
+
+// Error is the response of a failed request.
+type Error struct {
+	Error string `json:"error"`
+}
+
+// handler serves the admin API of a runner.
+type handler struct {
+	runner    *runner.Runner
+	authorize func(*http.Request) error
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.authorize != nil {
+		if err := h.authorize(r); err != nil {
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+
+	switch {
+	case path == "status":
+		h.route(w, r, map[string]http.HandlerFunc{http.MethodGet: h.status})
+	case path == "scale":
+		h.route(w, r, map[string]http.HandlerFunc{http.MethodPost: h.scale})
+	case path == "jobs":
+		h.route(w, r, map[string]http.HandlerFunc{http.MethodGet: h.list})
+	case strings.HasPrefix(path, "jobs/") && !strings.Contains(path[len("jobs/"):], "/"):
+		id := job.ID(path[len("jobs/"):])
+		h.route(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: func(w http.ResponseWriter, _ *http.Request) {
+				h.get(w, id)
+			},
+			http.MethodDelete: func(w http.ResponseWriter, _ *http.Request) {
+				h.cancel(w, id)
+			},
+		})
+	case path == "stop":
+		h.route(w, r, map[string]http.HandlerFunc{http.MethodPost: h.stop})
+	case path == "drain":
+		h.route(w, r, map[string]http.HandlerFunc{http.MethodPost: h.drain})
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// route calls the handler of the request method.
+func (h *handler) route(w http.ResponseWriter, r *http.Request, methods map[string]http.HandlerFunc) {
+	if handle, ok := methods[r.Method]; ok {
+		handle(w, r)
+		return
+	}
+
+	allowed := make([]string, 0, len(methods))
+	for method := range methods {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
+
+func (h *handler) status(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.currentStatus())
+}
+
+// scale starts or stops workers to reach given concurrency. Only a running runner is scaled.
+func (h *handler) scale(w http.ResponseWriter, r *http.Request) {
+	var req Scale
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Concurrency == nil || *req.Concurrency < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("concurrency must be zero or positive"))
+		return
+	}
+
+	if err := h.runner.SetConcurrency(*req.Concurrency); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.currentStatus())
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	var filter runner.Filter
+
+	if values := r.URL.Query()["state"]; len(values) > 0 {
+		states := make([]runner.State, 0, len(values))
+		for _, value := range values {
+			state, ok := parseState(value)
+			if !ok {
+				writeError(w, http.StatusBadRequest, errors.New("unknown state "+value))
+				return
+			}
+			states = append(states, state)
+		}
+		filter = runner.InState(states...)
+	}
+
+	infos := h.runner.List(filter)
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].EnqueuedAt.Before(infos[j].EnqueuedAt)
+	})
+
+	jobs := make([]Job, len(infos))
+	for i, info := range infos {
+		jobs[i] = newJob(info)
+	}
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (h *handler) get(w http.ResponseWriter, id job.ID) {
+	info, ok := h.runner.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newJob(info))
+}
+
+// cancel asks a job which is not finished to stop. The job is canceled asynchronously.
+func (h *handler) cancel(w http.ResponseWriter, id job.ID) {
+	info, ok := h.runner.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	if info.State.IsFinished() {
+		writeError(w, http.StatusConflict, errors.New("job is "+info.State.String()))
+		return
+	}
+
+	h.runner.Cancel(id)
+
+	writeJSON(w, http.StatusAccepted, newJob(info))
+}
+
+func (h *handler) stop(w http.ResponseWriter, _ *http.Request) {
+	h.runner.Stop()
+	writeJSON(w, http.StatusOK, h.currentStatus())
+}
+
+func (h *handler) drain(w http.ResponseWriter, _ *http.Request) {
+	h.runner.Drain()
+	writeJSON(w, http.StatusAccepted, h.currentStatus())
+}
+
+func (h *handler) currentStatus() Status {
+	s := h.runner.Status()
+
+	pending := make(map[string]int, len(s.PendingJobs))
+	for priority, count := range s.PendingJobs {
+		pending[strconv.Itoa(int(priority))] = count
+	}
+
+	return Status{
+		Concurrency:   s.Concurrency,
+		RunningJobs:   s.RunningJobs,
+		PendingJobs:   pending,
+		DelayedJobs:   s.DelayedJobs,
+		ThrottledJobs: s.ThrottledJobs,
+		LeakedJobs:    s.LeakedJobs,
+		DroppedEvents: s.DroppedEvents,
+		Draining:      s.Draining,
+	}
+}
+
+// New creates a handler for given runner.
+func New(r *runner.Runner, c Config) http.Handler {
+	return &handler{
+		runner:    r,
+		authorize: c.Authorize,
+	}
+}
+
+func newJob(info runner.JobInfo) Job {
+	j := Job{
+		ID:             info.ID,
+		IdempotencyKey: info.IdempotencyKey,
+		State:          info.State.String(),
+		Priority:       int(info.Priority),
+		EnqueuedAt:     info.EnqueuedAt,
+		ScheduledAt:    optionalTime(info.ScheduledAt),
+		StartedAt:      optionalTime(info.StartedAt),
+		FinishedAt:     optionalTime(info.FinishedAt),
+		Attempts:       info.Attempts,
+	}
+	if info.Err != nil {
+		j.Error = info.Err.Error()
+	}
+
+	return j
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func parseState(s string) (runner.State, bool) {
+	for state := runner.StateQueued; state <= runner.StateCanceled; state++ {
+		if state.String() == s {
+			return state, true
+		}
+	}
+	return 0, false
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v) // nolint:errcheck
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, Error{Error: err.Error()})
+}
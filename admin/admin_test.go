@@ -0,0 +1,186 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/admin"
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_Status(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 2})
+	r.Start()
+	defer r.Stop()
+
+	server := httptest.NewServer(admin.New(r, admin.Config{}))
+	defer server.Close()
+
+	var status admin.Status
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, server.URL+"/status", "", &status))
+	assert.Equal(t, 2, status.Concurrency)
+	assert.False(t, status.Draining)
+
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, server.URL+"/scale", `{"concurrency": 5}`, &status))
+	assert.Equal(t, 5, status.Concurrency)
+
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, server.URL+"/scale", `{"concurrency": 1}`, &status))
+	assert.Equal(t, 1, status.Concurrency)
+
+	var e admin.Error
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, server.URL+"/scale", `{"concurrency": -1}`, &e))
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, server.URL+"/scale", `{}`, &e))
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodPost, server.URL+"/scale", `{`, &e))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, request(t, http.MethodDelete, server.URL+"/status", "", &e))
+	assert.Equal(t, "method not allowed", e.Error)
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, server.URL+"/unknown", "", &e))
+}
+
+func TestHandler_ScaleNotRunning(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 2})
+
+	server := httptest.NewServer(admin.New(r, admin.Config{}))
+	defer server.Close()
+
+	var e admin.Error
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodPost, server.URL+"/scale", `{"concurrency": 5}`, &e))
+	assert.Equal(t, runner.ErrNotRunning.Error(), e.Error)
+	assert.Equal(t, 2, r.Status().Concurrency)
+}
+
+func TestHandler_Jobs(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+	defer r.Stop()
+
+	server := httptest.NewServer(admin.New(r, admin.Config{}))
+	defer server.Close()
+
+	blocking := &blockingTask{started: make(chan struct{}), release: make(chan struct{})}
+	running, err := job.New(blocking)
+	assert.NoError(t, err)
+	delayed, err := job.New(&blockingTask{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Enqueue(running))
+	assert.NoError(t, r.EnqueueAfter(time.Hour, delayed))
+	<-blocking.started
+
+	var jobs []admin.Job
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, server.URL+"/jobs", "", &jobs))
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, running.ID(), jobs[0].ID)
+	assert.Equal(t, "running", jobs[0].State)
+	assert.NotNil(t, jobs[0].StartedAt)
+	assert.Equal(t, "scheduled", jobs[1].State)
+	assert.NotNil(t, jobs[1].ScheduledAt)
+
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, server.URL+"/jobs?state=scheduled&state=failed", "", &jobs))
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, delayed.ID(), jobs[0].ID)
+
+	var e admin.Error
+	assert.Equal(t, http.StatusBadRequest, request(t, http.MethodGet, server.URL+"/jobs?state=unknown", "", &e))
+
+	var j admin.Job
+	assert.Equal(t, http.StatusAccepted, request(t, http.MethodDelete, server.URL+"/jobs/"+string(delayed.ID()), "", &j))
+	assert.Equal(t, delayed.ID(), j.ID)
+
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, server.URL+"/jobs/"+string(delayed.ID()), "", &j))
+	assert.Equal(t, "canceled", j.State)
+	assert.Equal(t, runner.ErrCanceled.Error(), j.Error)
+
+	assert.Equal(t, http.StatusConflict, request(t, http.MethodDelete, server.URL+"/jobs/"+string(delayed.ID()), "", &e))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodDelete, server.URL+"/jobs/unknown", "", &e))
+	assert.Equal(t, http.StatusNotFound, request(t, http.MethodGet, server.URL+"/jobs/unknown", "", &e))
+
+	close(blocking.release)
+}
+
+func TestHandler_StopAndDrain(t *testing.T) {
+	r := runner.New(runner.Config{})
+	r.Start()
+
+	server := httptest.NewServer(admin.New(r, admin.Config{}))
+	defer server.Close()
+
+	var status admin.Status
+	assert.Equal(t, http.StatusAccepted, request(t, http.MethodPost, server.URL+"/drain", "", &status))
+	assert.True(t, status.Draining)
+
+	assert.Equal(t, http.StatusOK, request(t, http.MethodPost, server.URL+"/stop", "", &status))
+	assert.False(t, status.Draining)
+
+	j, err := job.New(&blockingTask{})
+	assert.NoError(t, err)
+	assert.Error(t, r.Enqueue(j))
+}
+
+func TestHandler_Authorize(t *testing.T) {
+	r := runner.New(runner.Config{})
+
+	handler := admin.New(r, admin.Config{
+		Authorize: func(req *http.Request) error {
+			if req.Header.Get("Authorization") != "Bearer secret" {
+				return errors.New("invalid token")
+			}
+			return nil
+		},
+	})
+
+	// Mounted under a prefix
+	mux := http.NewServeMux()
+	mux.Handle("/admin/", http.StripPrefix("/admin", handler))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var e admin.Error
+	assert.Equal(t, http.StatusUnauthorized, request(t, http.MethodGet, server.URL+"/admin/status", "", &e))
+	assert.Equal(t, "invalid token", e.Error)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/admin/status", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func request(t *testing.T, method, url, body string, response interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+
+	res, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	defer res.Body.Close()
+
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(response))
+
+	return res.StatusCode
+}
+
+type blockingTask struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (t *blockingTask) Run(*job.Job) (interface{}, error) {
+	close(t.started)
+	<-t.release
+	return nil, nil
+}
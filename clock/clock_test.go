@@ -0,0 +1,50 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	c := clock.New()
+
+	assert.WithinDuration(t, time.Now(), c.Now(), time.Second)
+
+	timer := c.NewTimer(time.Millisecond)
+	<-timer.C()
+	assert.False(t, timer.Stop())
+}
+
+func TestFake(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := clock.NewFake(start)
+
+	first := c.NewTimer(time.Second)
+	second := c.NewTimer(time.Minute)
+	stopped := c.NewTimer(time.Second)
+	assert.Equal(t, 3, c.Timers())
+
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+
+	c.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), c.Now())
+	assert.Equal(t, start.Add(time.Second), <-first.C())
+	assert.Equal(t, 1, c.Timers())
+
+	select {
+	case <-second.C():
+		t.Fatal("expected timer to wait")
+	default:
+	}
+
+	c.Advance(time.Hour)
+	assert.Equal(t, start.Add(time.Hour+time.Second), <-second.C())
+	assert.Equal(t, 0, c.Timers())
+
+	immediate := c.NewTimer(0)
+	assert.Equal(t, c.Now(), <-immediate.C())
+}
@@ -0,0 +1,246 @@
+// Package cron parses cron expressions and computes their activation times.
+//
+// Supported are the standard 5 fields (minute, hour, day of month, month, day of week)
+// with lists (1,2), ranges (1-5), steps (*/10, 1-30/5), month and day names (JAN, MON),
+// the @yearly, @annually, @monthly, @weekly, @daily, @midnight and @hourly shorthands,
+// and fixed intervals with @every followed by a duration (@every 10s).
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes activation times.
+type Schedule interface {
+	// Next returns the first activation time after given time.
+	// The zero time is returned if there is no activation.
+	Next(time.Time) time.Time
+}
+
+// Parse creates a schedule from a cron expression.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval: %w", err)
+		}
+		if d <= 0 {
+			return nil, errors.New("interval must be positive")
+		}
+		return every(d), nil
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		expr, ok := shorthands[spec]
+		if !ok {
+			return nil, fmt.Errorf("unknown shorthand %q", spec)
+		}
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	s := &specSchedule{}
+	bits := []*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+
+	for i, f := range fields {
+		b, err := parseField(f, bounds[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s field %q: %w", bounds[i].name, f, err)
+		}
+		*bits[i] = b
+	}
+
+	// Sunday can be written as 0 or 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return s, nil
+}
+
+// every is a schedule activated at fixed intervals.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// specSchedule has one bit set for every allowed value of a field.
+type specSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// maxYears limits the search for expressions which never match (30 February).
+const maxYears = 5
+
+func (s *specSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + maxYears
+
+	for t.Year() <= limit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchDay follows the cron rule: if both day fields are restricted, a day matches any of them.
+func (s *specSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+type bound struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+// nolint:gochecknoglobals
+var bounds = []bound{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// nolint:gochecknoglobals
+var shorthands = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+func parseField(field string, b bound) (uint64, error) {
+	var bits uint64
+
+	for _, term := range strings.Split(field, ",") {
+		t, err := parseTerm(term, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= t
+	}
+
+	return bits, nil
+}
+
+// parseTerm parses *, a, a-b, */step, a/step and a-b/step.
+func parseTerm(term string, b bound) (uint64, error) {
+	rng, step := term, 1
+
+	if i := strings.Index(term, "/"); i >= 0 {
+		rng = term[:i]
+
+		s, err := strconv.Atoi(term[i+1:])
+		if err != nil || s <= 0 {
+			return 0, fmt.Errorf("invalid step in %q", term)
+		}
+		step = s
+	}
+
+	var start, end int
+
+	switch {
+	case rng == "*":
+		start, end = b.min, b.max
+	case strings.Contains(rng, "-"):
+		parts := strings.SplitN(rng, "-", 2)
+
+		var err error
+		if start, err = parseValue(parts[0], b); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(parts[1], b); err != nil {
+			return 0, err
+		}
+	default:
+		var err error
+		if start, err = parseValue(rng, b); err != nil {
+			return 0, err
+		}
+
+		end = start
+		if step > 1 {
+			end = b.max
+		}
+	}
+
+	if start > end {
+		return 0, fmt.Errorf("invalid range in %q", term)
+	}
+
+	var bits uint64
+	for v := start; v <= end; v += step {
+		bits |= 1 << uint(v)
+	}
+
+	return bits, nil
+}
+
+func parseValue(value string, b bound) (int, error) {
+	if v, ok := b.names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, b.min, b.max)
+	}
+
+	return v, nil
+}
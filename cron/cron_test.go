@@ -0,0 +1,157 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/andreiavrammsd/workexec/cron"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Errors(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@often",
+		"@every",
+		"@every 10",
+		"@every -1s",
+	}
+
+	for _, spec := range specs {
+		s, err := cron.Parse(spec)
+		assert.Nil(t, s, spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// Wednesday
+	start := time.Date(2020, time.January, 1, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected []time.Time
+	}{
+		{
+			spec: "* * * * *",
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 31, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 10, 32, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "*/20 9-11 * * *",
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 40, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 11, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 11, 20, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 11, 40, 0, 0, time.UTC),
+				time.Date(2020, time.January, 2, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "0 12 * * MON,fri",
+			expected: []time.Time{
+				time.Date(2020, time.January, 3, 12, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 6, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "15 0 1 feb-mar *",
+			expected: []time.Time{
+				time.Date(2020, time.February, 1, 0, 15, 0, 0, time.UTC),
+				time.Date(2020, time.March, 1, 0, 15, 0, 0, time.UTC),
+				time.Date(2021, time.February, 1, 0, 15, 0, 0, time.UTC),
+			},
+		},
+		{
+			// restricted day of month and day of week match any of them
+			spec: "0 0 13 * 7",
+			expected: []time.Time{
+				time.Date(2020, time.January, 5, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 12, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 13, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 19, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "0 0 29 2 *",
+			expected: []time.Time{
+				time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "30/15 * * * *",
+			expected: []time.Time{
+				time.Date(2020, time.January, 1, 10, 45, 0, 0, time.UTC),
+				time.Date(2020, time.January, 1, 11, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "@daily",
+			expected: []time.Time{
+				time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, time.January, 3, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			spec: "@every 90s",
+			expected: []time.Time{
+				start.Add(time.Second * 90),
+				start.Add(time.Second * 180),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s, err := cron.Parse(test.spec)
+		assert.NoError(t, err, test.spec)
+
+		next := start
+		for _, expected := range test.expected {
+			next = s.Next(next)
+			assert.Equal(t, expected, next, test.spec)
+		}
+	}
+}
+
+func TestSchedule_NextNeverMatches(t *testing.T) {
+	s, err := cron.Parse("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestSchedule_WithFakeClock(t *testing.T) {
+	c := clock.NewFake(time.Date(2020, time.January, 1, 23, 58, 30, 0, time.UTC))
+
+	s, err := cron.Parse("*/2 * * * *")
+	assert.NoError(t, err)
+
+	var fired []time.Time
+	for i := 0; i < 3; i++ {
+		wait := s.Next(c.Now()).Sub(c.Now())
+		timer := c.NewTimer(wait)
+		c.Advance(wait)
+		fired = append(fired, <-timer.C())
+	}
+
+	assert.Equal(t, []time.Time{
+		time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, time.January, 2, 0, 2, 0, 0, time.UTC),
+		time.Date(2020, time.January, 2, 0, 4, 0, 0, time.UTC),
+	}, fired)
+}
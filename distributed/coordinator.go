@@ -0,0 +1,393 @@
+// Package distributed runs jobs on remote workers. A coordinator holds the jobs, usually through a runner queue,
+// and workers connected over any net.Conn pull them, run them and report the results.
+//
+// A job given to a worker is leased: the worker renews the lease while the job runs. If the lease expires
+// or the worker disconnects, the job is queued again for another worker. So a job may run more than once.
+//
+// Jobs are sent with job.Registry Marshal, coordinator and workers must register the same tasks.
+// Messages are JSON frames, prefixed by their length.
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+const leaseDuration = 30 * time.Second
+
+var (
+	// ErrClosed is the error of the remote jobs which did not finish when the coordinator was closed.
+	ErrClosed = errors.New("coordinator is closed")
+	// ErrLeaseExpired is the cause a worker cancels a job with when it lost the lease of the job.
+	ErrLeaseExpired = errors.New("lease expired")
+)
+
+// RemoteError is the error a job failed with on a worker.
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// CoordinatorConfig allows setup of the coordinator.
+type CoordinatorConfig struct {
+	// Registry marshals the jobs sent to workers.
+	Registry *job.Registry
+
+	// Lease is how long a worker owns a job without renewing it. Defaults to 30 seconds.
+	Lease time.Duration
+
+	// Clock is used for leases. Defaults to the system time.
+	Clock clock.Clock
+}
+
+// dispatch is a job which is waiting for or running on a worker.
+type dispatch struct {
+	id     job.ID
+	data   []byte
+	lease  *lease
+	done   chan struct{}
+	result json.RawMessage
+	err    error
+}
+
+// lease is the ownership of a job by a worker.
+type lease struct {
+	worker *workerConn
+	timer  clock.Timer
+	// released stops watching the lease
+	released chan struct{}
+}
+
+// workerConn is a connection of a worker to the coordinator.
+type workerConn struct {
+	conn net.Conn
+	out  *outbox
+	// credits is the number of jobs the worker can take
+	credits int
+}
+
+// Coordinator gives jobs to the workers connected to it.
+type Coordinator struct {
+	lock      sync.Mutex
+	registry  *job.Registry
+	lease     time.Duration
+	clock     clock.Clock
+	pending   []*dispatch
+	leased    map[job.ID]*dispatch
+	workers   []*workerConn
+	listeners []net.Listener
+	closed    bool
+}
+
+// remoteTask runs a job on a worker.
+type remoteTask struct {
+	coordinator *Coordinator
+	id          job.ID
+	data        []byte
+}
+
+func (t *remoteTask) RunContext(ctx context.Context) (interface{}, error) {
+	d, err := t.coordinator.submit(t.id, t.data)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-d.done:
+		return d.result, d.err
+	case <-ctx.Done():
+		t.coordinator.cancel(d)
+		return nil, context.Cause(ctx)
+	}
+}
+
+// Remote returns a job with the same ID, which runs given job on a worker when it is run.
+// It is usually enqueued to a runner: canceling it by ID cancels it on the worker.
+// The result of the job is the JSON encoding (json.RawMessage) of the result on the worker.
+// A job which failed on the worker has a RemoteError.
+func (c *Coordinator) Remote(j *job.Job, opts ...job.Option) (*job.Job, error) {
+	data, err := c.registry.Marshal(j)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &remoteTask{coordinator: c, id: j.ID(), data: data}
+	return job.NewContext(task, append(opts[:len(opts):len(opts)], job.WithID(j.ID()))...)
+}
+
+// Serve accepts worker connections until the listener is closed.
+func (c *Coordinator) Serve(listener net.Listener) error {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return ErrClosed
+	}
+	c.listeners = append(c.listeners, listener)
+	c.lock.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if isClosed(err) {
+				return nil
+			}
+			return err
+		}
+
+		go c.ServeConn(conn) // nolint:errcheck
+	}
+}
+
+// ServeConn talks to a worker until the connection is closed.
+// The jobs of the worker which did not finish are queued again.
+func (c *Coordinator) ServeConn(conn net.Conn) error {
+	w := &workerConn{conn: conn, out: newOutbox()}
+
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		conn.Close() // nolint:errcheck
+		return ErrClosed
+	}
+	c.workers = append(c.workers, w)
+	c.lock.Unlock()
+
+	go func() {
+		if err := w.out.run(conn); err != nil {
+			conn.Close() // nolint:errcheck
+		}
+	}()
+
+	defer c.disconnect(w)
+
+	for {
+		m, err := readFrame(conn)
+		if err != nil {
+			if isClosed(err) {
+				return nil
+			}
+			return err
+		}
+
+		c.handle(w, m)
+	}
+}
+
+// Workers returns the number of connected workers.
+func (c *Coordinator) Workers() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.workers)
+}
+
+// Close disconnects the workers, stops the listeners and fails the jobs which did not finish with ErrClosed.
+func (c *Coordinator) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+
+	for _, listener := range c.listeners {
+		listener.Close() // nolint:errcheck
+	}
+	for _, w := range c.workers {
+		w.conn.Close() // nolint:errcheck
+	}
+
+	for _, d := range c.pending {
+		d.finish(nil, ErrClosed)
+	}
+	c.pending = nil
+
+	for _, d := range c.leased {
+		c.release(d)
+		d.finish(nil, ErrClosed)
+	}
+	c.leased = make(map[job.ID]*dispatch)
+}
+
+func (c *Coordinator) handle(w *workerConn, m message) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch m.Type {
+	case pullMessage:
+		w.credits++
+		c.dispatch()
+	case heartbeatMessage:
+		if d, ok := c.leased[m.JobID]; ok && d.lease.worker == w {
+			c.release(d)
+			c.grant(d, w)
+		}
+	case resultMessage:
+		d, ok := c.leased[m.JobID]
+		if !ok || d.lease.worker != w {
+			// The job was canceled or given to another worker
+			return
+		}
+
+		c.release(d)
+		delete(c.leased, d.id)
+
+		var err error
+		if m.Error != "" {
+			err = &RemoteError{Message: m.Error}
+		}
+		d.finish(m.Result, err)
+	case jobMessage, cancelMessage:
+	}
+}
+
+// submit queues a job for the workers.
+func (c *Coordinator) submit(id job.ID, data []byte) (*dispatch, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		return nil, ErrClosed
+	}
+
+	d := &dispatch{id: id, data: data, done: make(chan struct{})}
+	c.pending = append(c.pending, d)
+	c.dispatch()
+
+	return d, nil
+}
+
+// cancel removes a job which is waiting, or asks the worker which runs it to cancel it.
+func (c *Coordinator) cancel(d *dispatch) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for i, pending := range c.pending {
+		if pending == d {
+			c.pending = append(c.pending[:i], c.pending[i+1:]...)
+			return
+		}
+	}
+
+	if c.leased[d.id] == d {
+		d.lease.worker.out.send(message{Type: cancelMessage, JobID: d.id})
+		c.release(d)
+		delete(c.leased, d.id)
+	}
+}
+
+// dispatch gives the waiting jobs to the workers which can take them. It must be called with the lock held.
+func (c *Coordinator) dispatch() {
+	for _, w := range c.workers {
+		for w.credits > 0 && len(c.pending) > 0 {
+			d := c.pending[0]
+			c.pending = c.pending[1:]
+			w.credits--
+
+			c.leased[d.id] = d
+			c.grant(d, w)
+			w.out.send(message{Type: jobMessage, JobID: d.id, Job: d.data, Lease: c.lease})
+		}
+	}
+}
+
+// grant leases a job to a worker. It must be called with the lock held.
+func (c *Coordinator) grant(d *dispatch, w *workerConn) {
+	l := &lease{worker: w, timer: c.clock.NewTimer(c.lease), released: make(chan struct{})}
+	d.lease = l
+
+	go func() {
+		select {
+		case <-l.timer.C():
+			c.expire(d, l)
+		case <-l.released:
+		}
+	}()
+}
+
+// release stops the lease of a job. It must be called with the lock held.
+func (c *Coordinator) release(d *dispatch) {
+	d.lease.timer.Stop()
+	close(d.lease.released)
+}
+
+// expire queues a job again if its lease was not renewed.
+func (c *Coordinator) expire(d *dispatch, l *lease) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if d.lease != l || c.leased[d.id] != d {
+		return
+	}
+
+	l.worker.out.send(message{Type: cancelMessage, JobID: d.id, Error: ErrLeaseExpired.Error()})
+	c.requeue(d)
+	c.dispatch()
+}
+
+// disconnect queues again the jobs of a worker which is gone.
+func (c *Coordinator) disconnect(w *workerConn) {
+	w.conn.Close() // nolint:errcheck
+	w.out.close()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for i, worker := range c.workers {
+		if worker == w {
+			c.workers = append(c.workers[:i], c.workers[i+1:]...)
+			break
+		}
+	}
+
+	if c.closed {
+		return
+	}
+
+	for _, d := range c.leased {
+		if d.lease.worker == w {
+			c.requeue(d)
+		}
+	}
+	c.dispatch()
+}
+
+// requeue puts a leased job in front of the waiting jobs. It must be called with the lock held.
+func (c *Coordinator) requeue(d *dispatch) {
+	c.release(d)
+	delete(c.leased, d.id)
+	c.pending = append([]*dispatch{d}, c.pending...)
+}
+
+func (d *dispatch) finish(result json.RawMessage, err error) {
+	d.result, d.err = result, err
+	close(d.done)
+}
+
+// NewCoordinator creates a coordinator without workers.
+func NewCoordinator(c CoordinatorConfig) *Coordinator {
+	if c.Lease <= 0 {
+		c.Lease = leaseDuration
+	}
+	if c.Clock == nil {
+		c.Clock = clock.New()
+	}
+
+	return &Coordinator{
+		registry: c.Registry,
+		lease:    c.Lease,
+		clock:    c.Clock,
+		leased:   make(map[job.ID]*dispatch),
+	}
+}
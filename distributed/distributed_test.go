@@ -0,0 +1,308 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrame(t *testing.T) {
+	var buf bytes.Buffer
+
+	sent := message{Type: jobMessage, JobID: "id", Job: json.RawMessage(`{"a":1}`), Lease: time.Second}
+	assert.NoError(t, writeFrame(&buf, sent))
+	assert.NoError(t, writeFrame(&buf, message{Type: pullMessage}))
+
+	received, err := readFrame(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, received)
+
+	received, err = readFrame(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, pullMessage, received.Type)
+
+	_, err = readFrame(&buf)
+	assert.True(t, isClosed(err))
+
+	_, err = readFrame(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}))
+	assert.Error(t, err)
+}
+
+func TestCoordinator_Runner(t *testing.T) {
+	registry := newRegistry(t)
+	coordinator := NewCoordinator(CoordinatorConfig{Registry: registry})
+	defer coordinator.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Two workers, one over TCP and one over a pipe
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go coordinator.Serve(listener) // nolint:errcheck
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	go NewWorker(WorkerConfig{Registry: registry, Concurrency: 2}).Run(ctx, conn) // nolint:errcheck
+
+	coordinatorConn, workerConn := net.Pipe()
+	go coordinator.ServeConn(coordinatorConn)                           // nolint:errcheck
+	go NewWorker(WorkerConfig{Registry: registry}).Run(ctx, workerConn) // nolint:errcheck
+
+	r := runner.New(runner.Config{Concurrency: 4})
+	r.Start()
+	defer r.Stop()
+
+	var ids []job.ID
+	for _, value := range []string{"a", "b", "c", "d", "e"} {
+		ids = append(ids, enqueue(t, coordinator, r, &echoTask{Value: value}))
+	}
+	failed := enqueue(t, coordinator, r, &echoTask{Err: "failed"})
+
+	for i, id := range ids {
+		info := waitFinished(t, r, id)
+		assert.Equal(t, runner.StateSucceeded, info.State)
+		assert.Equal(t, json.RawMessage(`"`+string(rune('a'+i))+`"`), info.Result)
+	}
+
+	info := waitFinished(t, r, failed)
+	assert.Equal(t, runner.StateFailed, info.State)
+	var remoteErr *RemoteError
+	assert.True(t, errors.As(info.Err, &remoteErr))
+	assert.Equal(t, "failed", remoteErr.Message)
+
+	assert.Equal(t, 2, coordinator.Workers())
+}
+
+func TestCoordinator_Cancel(t *testing.T) {
+	registry := newRegistry(t)
+	coordinator := NewCoordinator(CoordinatorConfig{Registry: registry})
+	defer coordinator.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	coordinatorConn, workerConn := net.Pipe()
+	go coordinator.ServeConn(coordinatorConn)                           // nolint:errcheck
+	go NewWorker(WorkerConfig{Registry: registry}).Run(ctx, workerConn) // nolint:errcheck
+
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+	defer r.Stop()
+
+	blocking := newBlockingTask("blocking")
+	id := enqueue(t, coordinator, r, blocking)
+	<-blockingTasks[blocking.Name].started
+
+	r.Cancel(id)
+
+	// The job is canceled on the worker
+	assert.ErrorIs(t, <-blockingTasks[blocking.Name].canceled, job.ErrCanceled)
+	assert.Equal(t, runner.StateCanceled, waitFinished(t, r, id).State)
+
+	// The worker can take another job
+	assert.Equal(t, runner.StateSucceeded, waitFinished(t, r, enqueue(t, coordinator, r, &echoTask{})).State)
+}
+
+func TestCoordinator_LeaseExpired(t *testing.T) {
+	registry := newRegistry(t)
+	fakeClock := clock.NewFake(time.Now())
+	coordinator := NewCoordinator(CoordinatorConfig{Registry: registry, Lease: time.Minute, Clock: fakeClock})
+	defer coordinator.Close()
+
+	// A worker which takes a job and hangs
+	coordinatorConn, hangingConn := net.Pipe()
+	defer hangingConn.Close()
+	go coordinator.ServeConn(coordinatorConn) // nolint:errcheck
+	assert.NoError(t, writeFrame(hangingConn, message{Type: pullMessage}))
+
+	remote, err := coordinator.Remote(mustJob(t, &echoTask{Value: "a"}))
+	assert.NoError(t, err)
+	future := remote.Run()
+
+	m, err := readFrame(hangingConn)
+	assert.NoError(t, err)
+	assert.Equal(t, jobMessage, m.Type)
+	assert.Equal(t, remote.ID(), m.JobID)
+	assert.Equal(t, time.Minute, m.Lease)
+
+	// A heartbeat renews the lease
+	first := currentLease(coordinator, m.JobID)
+	fakeClock.Advance(time.Second * 30)
+	assert.NoError(t, writeFrame(hangingConn, message{Type: heartbeatMessage, JobID: m.JobID}))
+	for currentLease(coordinator, m.JobID) == first {
+		time.Sleep(time.Millisecond)
+	}
+	fakeClock.Advance(time.Second * 45)
+	assert.Equal(t, 1, fakeClock.Timers())
+
+	// The lease expires and the job is given to another worker
+	fakeClock.Advance(time.Minute)
+
+	m, err = readFrame(hangingConn)
+	assert.NoError(t, err)
+	assert.Equal(t, cancelMessage, m.Type)
+	assert.Equal(t, ErrLeaseExpired.Error(), m.Error)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	coordinatorConn, workerConn := net.Pipe()
+	go coordinator.ServeConn(coordinatorConn)                                             // nolint:errcheck
+	go NewWorker(WorkerConfig{Registry: registry, Clock: fakeClock}).Run(ctx, workerConn) // nolint:errcheck
+
+	future.Wait()
+	assert.NoError(t, future.Error())
+	assert.Equal(t, json.RawMessage(`"a"`), future.Result())
+
+	// A late result of the first worker is ignored
+	assert.NoError(t, writeFrame(hangingConn, message{Type: resultMessage, JobID: remote.ID(), Error: "late"}))
+}
+
+func TestCoordinator_Disconnect(t *testing.T) {
+	registry := newRegistry(t)
+	coordinator := NewCoordinator(CoordinatorConfig{Registry: registry})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	coordinatorConn, workerConn := net.Pipe()
+	go coordinator.ServeConn(coordinatorConn) // nolint:errcheck
+	stopped := make(chan error)
+	go func() {
+		stopped <- NewWorker(WorkerConfig{Registry: registry}).Run(ctx, workerConn)
+	}()
+
+	blocking := newBlockingTask("disconnect")
+	remote, err := coordinator.Remote(mustJob(t, blocking))
+	assert.NoError(t, err)
+	future := remote.Run()
+	<-blockingTasks[blocking.Name].started
+
+	// Stopping the worker cancels its jobs, which are queued again
+	cancel()
+	assert.NoError(t, <-stopped)
+	assert.Error(t, <-blockingTasks[blocking.Name].canceled)
+
+	for coordinator.Workers() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	pending, err := coordinator.Remote(mustJob(t, &echoTask{}))
+	assert.NoError(t, err)
+	pendingFuture := pending.Run()
+
+	coordinator.Close()
+	future.Wait()
+	assert.ErrorIs(t, future.Error(), ErrClosed)
+	pendingFuture.Wait()
+	assert.ErrorIs(t, pendingFuture.Error(), ErrClosed)
+
+	assert.ErrorIs(t, coordinator.ServeConn(coordinatorConn), ErrClosed)
+}
+
+type echoTask struct {
+	Value string `json:"value"`
+	Err   string `json:"err"`
+}
+
+func (t *echoTask) Run(*job.Job) (interface{}, error) {
+	if t.Err != "" {
+		return nil, errors.New(t.Err)
+	}
+	return t.Value, nil
+}
+
+// blockingTasks are the signals of the blocking tasks by name, as tasks are sent as values.
+var blockingTasks = map[string]*blockingSignals{} // nolint:gochecknoglobals
+
+type blockingSignals struct {
+	started  chan struct{}
+	canceled chan error
+}
+
+type blockingTask struct {
+	Name string `json:"name"`
+}
+
+func (t *blockingTask) RunContext(ctx context.Context) (interface{}, error) {
+	signals := blockingTasks[t.Name]
+	close(signals.started)
+	<-ctx.Done()
+	signals.canceled <- context.Cause(ctx)
+	return nil, context.Cause(ctx)
+}
+
+func newBlockingTask(name string) *blockingTask {
+	blockingTasks[name] = &blockingSignals{started: make(chan struct{}), canceled: make(chan error, 1)}
+	return &blockingTask{Name: name}
+}
+
+func newRegistry(t *testing.T) *job.Registry {
+	t.Helper()
+
+	registry := job.NewRegistry()
+	assert.NoError(t, registry.Register("echo", func() interface{} { return &echoTask{} }))
+	assert.NoError(t, registry.Register("blocking", func() interface{} { return &blockingTask{} }))
+
+	return registry
+}
+
+func mustJob(t *testing.T, task interface{}) *job.Job {
+	t.Helper()
+
+	var j *job.Job
+	var err error
+	if contextTask, ok := task.(job.ContextTask); ok {
+		j, err = job.NewContext(contextTask)
+	} else {
+		j, err = job.New(task.(job.Task)) // nolint:errcheck
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return j
+}
+
+func enqueue(t *testing.T, coordinator *Coordinator, r *runner.Runner, task interface{}) job.ID {
+	t.Helper()
+
+	remote, err := coordinator.Remote(mustJob(t, task))
+	assert.NoError(t, err)
+	assert.NoError(t, r.Enqueue(remote))
+
+	return remote.ID()
+}
+
+func waitFinished(t *testing.T, r *runner.Runner, id job.ID) runner.JobInfo {
+	t.Helper()
+
+	timeout := time.After(time.Second * 10)
+	for {
+		if info, ok := r.Get(id); ok && info.State.IsFinished() {
+			return info
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("job %s did not finish", id)
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func currentLease(c *Coordinator, id job.ID) *lease {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.leased[id].lease
+}
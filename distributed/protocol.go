@@ -0,0 +1,141 @@
+package distributed
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+// maxFrameSize is the size of the largest message which is accepted.
+const maxFrameSize = 16 << 20
+
+type messageType uint8
+
+const (
+	// pullMessage is sent by a worker which can run one more job
+	pullMessage messageType = iota + 1
+	// jobMessage gives a job to a worker, with a lease
+	jobMessage
+	// heartbeatMessage renews the lease of a job
+	heartbeatMessage
+	// resultMessage is sent by a worker when a job is done
+	resultMessage
+	// cancelMessage asks a worker to cancel a job
+	cancelMessage
+)
+
+// message is a frame of the protocol.
+type message struct {
+	Type   messageType     `json:"type"`
+	JobID  job.ID          `json:"job_id,omitempty"`
+	Job    json.RawMessage `json:"job,omitempty"`
+	Lease  time.Duration   `json:"lease,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// writeFrame writes a message prefixed by its length.
+func writeFrame(w io.Writer, m message) error {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+
+	_, err = w.Write(frame)
+	return err
+}
+
+// readFrame reads a message prefixed by its length.
+func readFrame(r io.Reader) (message, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return message{}, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return message{}, fmt.Errorf("frame of %d bytes exceeds limit of %d", size, maxFrameSize)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return message{}, err
+	}
+
+	var m message
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return message{}, err
+	}
+
+	return m, nil
+}
+
+// outbox writes messages to a connection in order, without blocking the sender.
+type outbox struct {
+	lock     sync.Mutex
+	messages []message
+	notify   chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (o *outbox) send(m message) {
+	o.lock.Lock()
+	o.messages = append(o.messages, m)
+	o.lock.Unlock()
+
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run writes the sent messages until the outbox is closed or writing fails.
+func (o *outbox) run(w io.Writer) error {
+	for {
+		select {
+		case <-o.notify:
+			o.lock.Lock()
+			messages := o.messages
+			o.messages = nil
+			o.lock.Unlock()
+
+			for _, m := range messages {
+				if err := writeFrame(w, m); err != nil {
+					return err
+				}
+			}
+		case <-o.done:
+			return nil
+		}
+	}
+}
+
+func (o *outbox) close() {
+	o.once.Do(func() {
+		close(o.done)
+	})
+}
+
+func newOutbox() *outbox {
+	return &outbox{
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// isClosed tells if a read failed because the connection was closed.
+func isClosed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe)
+}
@@ -0,0 +1,185 @@
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+// WorkerConfig allows setup of the worker.
+type WorkerConfig struct {
+	// Registry unmarshals the jobs received from the coordinator.
+	Registry *job.Registry
+
+	// Concurrency is the number of jobs run at the same time. Defaults to one.
+	Concurrency int
+
+	// Clock is used for renewing leases. Defaults to the system time.
+	Clock clock.Clock
+}
+
+// Worker pulls jobs from a coordinator and runs them.
+type Worker struct {
+	registry    *job.Registry
+	concurrency int
+	clock       clock.Clock
+}
+
+// session is the state of a worker connected to a coordinator.
+type session struct {
+	lock    sync.Mutex
+	running map[job.ID]*job.Job
+	wg      sync.WaitGroup
+	out     *outbox
+}
+
+// Run runs jobs received on given connection until the context is done or the connection is closed.
+// The jobs which are running are canceled.
+func (w *Worker) Run(ctx context.Context, conn net.Conn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &session{running: make(map[job.ID]*job.Job), out: newOutbox()}
+
+	go func() {
+		if err := s.out.run(conn); err != nil {
+			conn.Close() // nolint:errcheck
+		}
+	}()
+	defer s.out.close()
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close() // nolint:errcheck
+	})
+	defer stop()
+
+	for i := 0; i < w.concurrency; i++ {
+		s.out.send(message{Type: pullMessage})
+	}
+
+	err := w.receive(ctx, conn, s)
+
+	cancel()
+	conn.Close() // nolint:errcheck
+	s.wg.Wait()
+
+	if isClosed(err) {
+		return nil
+	}
+	return err
+}
+
+func (w *Worker) receive(ctx context.Context, conn net.Conn, s *session) error {
+	for {
+		m, err := readFrame(conn)
+		if err != nil {
+			return err
+		}
+
+		switch m.Type {
+		case jobMessage:
+			w.start(ctx, s, m)
+		case cancelMessage:
+			s.lock.Lock()
+			j, ok := s.running[m.JobID]
+			s.lock.Unlock()
+
+			if ok {
+				var cause error
+				if m.Error == ErrLeaseExpired.Error() {
+					cause = ErrLeaseExpired
+				}
+				j.Cancel(cause)
+			}
+		case pullMessage, heartbeatMessage, resultMessage:
+		}
+	}
+}
+
+// start runs a job, renewing its lease until it is done.
+func (w *Worker) start(ctx context.Context, s *session, m message) {
+	j, err := w.registry.Unmarshal(m.Job)
+	if err != nil {
+		s.out.send(message{Type: resultMessage, JobID: m.JobID, Error: err.Error()})
+		s.out.send(message{Type: pullMessage})
+		return
+	}
+
+	s.lock.Lock()
+	s.running[m.JobID] = j
+	s.lock.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		future := j.RunContext(ctx)
+		w.heartbeat(s.out, m.JobID, m.Lease, future.Done())
+
+		s.lock.Lock()
+		delete(s.running, m.JobID)
+		s.lock.Unlock()
+
+		// A job canceled because the worker stops is not reported, so the coordinator gives it to another worker
+		if ctx.Err() != nil {
+			return
+		}
+
+		result := message{Type: resultMessage, JobID: m.JobID}
+		err := future.Error()
+		if err == nil && future.IsCanceled() {
+			err = j.Cause()
+		}
+		if err == nil {
+			result.Result, err = json.Marshal(future.Result())
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		s.out.send(result)
+		s.out.send(message{Type: pullMessage})
+	}()
+}
+
+// heartbeat renews the lease of a job three times during the lease, until the job is done.
+func (w *Worker) heartbeat(out *outbox, id job.ID, lease time.Duration, done <-chan struct{}) {
+	interval := lease / 3
+	if interval <= 0 {
+		<-done
+		return
+	}
+
+	for {
+		timer := w.clock.NewTimer(interval)
+
+		select {
+		case <-timer.C():
+			out.send(message{Type: heartbeatMessage, JobID: id})
+		case <-done:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// NewWorker creates a worker.
+func NewWorker(c WorkerConfig) *Worker {
+	if c.Concurrency <= 0 {
+		c.Concurrency = 1
+	}
+	if c.Clock == nil {
+		c.Clock = clock.New()
+	}
+
+	return &Worker{
+		registry:    c.Registry,
+		concurrency: c.Concurrency,
+		clock:       c.Clock,
+	}
+}
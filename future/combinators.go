@@ -0,0 +1,106 @@
+package future
+
+import (
+	"context"
+	"errors"
+)
+
+// Map returns a running future with the result of given function applied to the result of the future.
+// The function is not called if the future failed, the error is passed on.
+// The future is run if it was not. Canceling the returned future cancels the future and the other way around.
+func Map(f *Future, fn func(interface{}) (interface{}, error)) *Future {
+	return Then(f, func(result interface{}, err error) (interface{}, error) {
+		if err != nil {
+			return nil, err
+		}
+		return fn(result)
+	})
+}
+
+// Then returns a running future with the result of given function, called with the result and error of the future.
+// The future is run if it was not. Canceling the returned future cancels the future and the other way around.
+func Then(f *Future, fn func(interface{}, error) (interface{}, error)) *Future {
+	return combine(f, func(_ *Future, result interface{}, err error) (interface{}, error) {
+		return fn(result, err)
+	})
+}
+
+// Recover returns a running future which turns the error of the future into the result of given function.
+// The result of a successful future is passed on.
+// The future is run if it was not. Canceling the returned future cancels the future and the other way around.
+func Recover(f *Future, fn func(error) (interface{}, error)) *Future {
+	return Then(f, func(result interface{}, err error) (interface{}, error) {
+		if err == nil {
+			return result, nil
+		}
+		return fn(err)
+	})
+}
+
+// FlatMap returns a running future with the result of the future returned by given function,
+// called with the result of the future. The function is not called if the future failed.
+// The futures are run if they were not. Canceling the returned future cancels both futures,
+// canceling any of them cancels the returned future.
+func FlatMap(f *Future, fn func(interface{}) (*Future, error)) *Future {
+	return combine(f, func(combined *Future, result interface{}, err error) (interface{}, error) {
+		if err != nil {
+			return nil, err
+		}
+
+		next, err := fn(result)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return nil, errors.New("nil future returned")
+		}
+
+		return await(combined, next)
+	})
+}
+
+// combineTask waits for a future and computes a result from its outcome.
+type combineTask struct {
+	combined *Future
+	future   *Future
+	fn       func(*Future, interface{}, error) (interface{}, error)
+}
+
+func (t *combineTask) RunContext(context.Context) (interface{}, error) {
+	result, err := await(t.combined, t.future)
+	if t.future.IsCanceled() {
+		return nil, err
+	}
+
+	return t.fn(t.combined, result, err)
+}
+
+// combine returns a running future which applies given function to the outcome of a future.
+func combine(f *Future, fn func(*Future, interface{}, error) (interface{}, error)) *Future {
+	task := &combineTask{future: f, fn: fn}
+	task.combined, _ = NewContext(task) // nolint:errcheck
+	task.combined.Run()
+
+	return task.combined
+}
+
+// await runs a future and waits for it. Each of the combined future and the future
+// is canceled when the other one is.
+func await(combined, f *Future) (interface{}, error) {
+	stop := context.AfterFunc(combined.ctx, func() {
+		f.CancelCause(combined.Cause())
+	})
+	defer stop()
+
+	f.Run()
+	result, err := f.Result()
+
+	if f.IsCanceled() {
+		combined.CancelCause(f.Cause())
+		if err == nil {
+			err = f.Cause()
+		}
+	}
+
+	return result, err
+}
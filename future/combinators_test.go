@@ -0,0 +1,126 @@
+package future_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andreiavrammsd/workexec/future"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	taskFuture, err := future.New(&divideTask{a: 4, b: 2})
+	assert.NoError(t, err)
+
+	mapped := future.Map(taskFuture, func(result interface{}) (interface{}, error) {
+		return result.(int) * 10, nil
+	})
+	result, err := mapped.Result()
+	assert.Equal(t, 20, result)
+	assert.NoError(t, err)
+
+	failed, err := future.New(&divideTask{a: 4, b: 0})
+	assert.NoError(t, err)
+
+	called := false
+	mapped = future.Map(failed, func(result interface{}) (interface{}, error) {
+		called = true
+		return result, nil
+	})
+	result, err = mapped.Result()
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "division by zero")
+	assert.False(t, called)
+}
+
+func TestThen(t *testing.T) {
+	taskFuture, err := future.New(&divideTask{a: 4, b: 0})
+	assert.NoError(t, err)
+
+	then := future.Then(taskFuture, func(result interface{}, err error) (interface{}, error) {
+		return err.Error(), nil
+	})
+	result, err := then.Result()
+	assert.Equal(t, "division by zero", result)
+	assert.NoError(t, err)
+}
+
+func TestRecover(t *testing.T) {
+	failed, err := future.New(&divideTask{a: 4, b: 0})
+	assert.NoError(t, err)
+
+	recovered := future.Recover(failed, func(error) (interface{}, error) {
+		return 0, nil
+	})
+	result, err := recovered.Result()
+	assert.Equal(t, 0, result)
+	assert.NoError(t, err)
+
+	succeeded, err := future.New(&divideTask{a: 4, b: 2})
+	assert.NoError(t, err)
+
+	recovered = future.Recover(succeeded, func(error) (interface{}, error) {
+		return 0, nil
+	})
+	result, err = recovered.Result()
+	assert.Equal(t, 2, result)
+	assert.NoError(t, err)
+}
+
+func TestFlatMap(t *testing.T) {
+	taskFuture, err := future.New(&divideTask{a: 8, b: 2})
+	assert.NoError(t, err)
+
+	chained := future.FlatMap(taskFuture, func(result interface{}) (*future.Future, error) {
+		return future.New(&divideTask{a: result.(int), b: 2})
+	})
+	result, err := chained.Result()
+	assert.Equal(t, 2, result)
+	assert.NoError(t, err)
+
+	chained = future.FlatMap(taskFuture, func(result interface{}) (*future.Future, error) {
+		return nil, errors.New("no future")
+	})
+	_, err = chained.Result()
+	assert.EqualError(t, err, "no future")
+}
+
+func TestMap_CancelUpstream(t *testing.T) {
+	task := &contextTask{block: true}
+	taskFuture, err := future.NewContext(task)
+	assert.NoError(t, err)
+
+	mapped := future.Map(taskFuture, func(result interface{}) (interface{}, error) {
+		return result, nil
+	})
+
+	cause := errors.New("not needed anymore")
+	mapped.CancelCause(cause)
+
+	_, err = mapped.Result()
+	assert.Equal(t, cause, err)
+	taskFuture.Wait()
+	assert.True(t, taskFuture.IsCanceled())
+	assert.Equal(t, cause, taskFuture.Cause())
+}
+
+func TestFlatMap_CancelDownstream(t *testing.T) {
+	taskFuture, err := future.New(&divideTask{a: 4, b: 2})
+	assert.NoError(t, err)
+
+	inner, err := future.NewContext(&contextTask{block: true})
+	assert.NoError(t, err)
+
+	chained := future.FlatMap(taskFuture, func(interface{}) (*future.Future, error) {
+		return inner, nil
+	})
+
+	cause := errors.New("stopped")
+	inner.CancelCause(cause)
+
+	result, err := chained.Result()
+	assert.Nil(t, result)
+	assert.Equal(t, cause, err)
+	assert.True(t, chained.IsCanceled())
+	assert.Equal(t, cause, chained.Cause())
+}
@@ -2,19 +2,56 @@
 package future
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sync"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/internal/deadline"
+)
+
+var (
+	// ErrCanceled is the cause of a future canceled with Cancel.
+	ErrCanceled = errors.New("canceled")
+	// ErrDeadlineExceeded is the error of a task which did not finish before its deadline.
+	ErrDeadlineExceeded = fmt.Errorf("future %w", context.DeadlineExceeded)
 )
 
 // Future represents a task which executes async work.
 type Future struct {
-	task     Task
+	task     interface{}
+	call     func() (interface{}, error)
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
 	done     chan struct{}
 	result   interface{}
 	err      error
-	canceled bool
 	on       bool
+	timeout  time.Duration
+	deadline time.Time
 	lock     sync.RWMutex
+
+	panicHandler PanicHandler
+}
+
+// Option allows setup of a future.
+type Option func(*Future)
+
+// WithTimeout cancels the task if it is still running after given duration since Run was called.
+// The future is done with ErrDeadlineExceeded then, even if the task does not stop.
+func WithTimeout(timeout time.Duration) Option {
+	return func(f *Future) {
+		f.timeout = timeout
+	}
+}
+
+// WithDeadline cancels the task if it is still running at given time.
+// The future is done with ErrDeadlineExceeded then, even if the task does not stop.
+func WithDeadline(deadline time.Time) Option {
+	return func(f *Future) {
+		f.deadline = deadline
+	}
 }
 
 // Run executes the Task async.
@@ -58,31 +95,51 @@ func (f *Future) Result() (interface{}, error) {
 
 // Cancel asks the task to stop.
 func (f *Future) Cancel() {
-	f.lock.Lock()
-	f.canceled = true
-	f.lock.Unlock()
+	f.cancel(ErrCanceled)
+}
+
+// CancelCause asks the task to stop, canceling its context with given error as cause.
+// Only the first cancellation is taken into account.
+func (f *Future) CancelCause(err error) {
+	if err == nil {
+		err = ErrCanceled
+	}
+	f.cancel(err)
 }
 
 // IsCanceled returns true if task was canceled.
 func (f *Future) IsCanceled() bool {
-	f.lock.RLock()
-	defer f.lock.RUnlock()
-	return f.canceled
+	return f.ctx.Err() != nil
+}
+
+// Cause returns the error the task was canceled with, or nil if not canceled.
+func (f *Future) Cause() error {
+	return context.Cause(f.ctx)
 }
 
 func (f *Future) run() {
-	defer close(f.done)
+	expiry := &deadline.Expiry{}
+	if at, ok := deadline.From(time.Now(), f.deadline, f.timeout); ok {
+		timer := time.AfterFunc(time.Until(at), func() {
+			if expiry.Expire() {
+				f.cancel(ErrDeadlineExceeded)
+				f.expire()
+			}
+		})
+		defer timer.Stop()
+	}
 
-	f.result, f.err = f.task.Run(f.IsCanceled)
+	result, err := f.protect()
+	if !expiry.Return() {
+		// The future was done at its deadline, the task which ignored the cancellation is dropped
+		return
+	}
 
-	if task, ok := f.task.(CanceledTask); ok {
-		f.lock.RLock()
-		if f.canceled {
-			f.lock.RUnlock()
-			task.OnCancel()
-			return
-		}
-		f.lock.RUnlock()
+	defer close(f.done)
+	f.result, f.err = result, err
+
+	if f.IsCanceled() && f.notifyCancel(f.Cause()) {
+		return
 	}
 
 	if task, ok := f.task.(SuccessfulTask); ok {
@@ -98,16 +155,74 @@ func (f *Future) run() {
 	}
 }
 
+// expire is done at the deadline, without waiting for the task to return. The deadline is reported
+// as a cancellation if the task is cancelable, else as an error.
+func (f *Future) expire() {
+	defer close(f.done)
+
+	f.result, f.err = nil, ErrDeadlineExceeded
+
+	if f.notifyCancel(f.err) {
+		return
+	}
+	if task, ok := f.task.(FailedTask); ok {
+		task.OnError(f.err)
+	}
+}
+
+// notifyCancel notifies a task which handles cancellation, with given cause if it accepts one.
+// It returns false if the task does not handle cancellation.
+func (f *Future) notifyCancel(cause error) bool {
+	if task, ok := f.task.(CanceledCauseTask); ok {
+		task.OnCancelCause(cause)
+		return true
+	}
+	if task, ok := f.task.(CanceledTask); ok {
+		task.OnCancel()
+		return true
+	}
+	return false
+}
+
 // New creates a new future with a given task.
-func New(task Task) (*Future, error) {
+func New(task Task, opts ...Option) (*Future, error) {
+	if task == nil {
+		return nil, errors.New("nil task passed")
+	}
+
+	// Polling tasks are adapted to the context of the future, they check it with IsCanceled.
+	future := newFuture(task, opts)
+	future.call = func() (interface{}, error) {
+		return task.Run(future.IsCanceled)
+	}
+
+	return future, nil
+}
+
+// NewContext creates a new future with a given task which is canceled through its context.
+func NewContext(task ContextTask, opts ...Option) (*Future, error) {
 	if task == nil {
 		return nil, errors.New("nil task passed")
 	}
 
+	future := newFuture(task, opts)
+	future.call = func() (interface{}, error) {
+		return task.RunContext(future.ctx)
+	}
+
+	return future, nil
+}
+
+func newFuture(task interface{}, opts []Option) *Future {
 	future := &Future{
 		task: task,
 		done: make(chan struct{}),
 	}
+	future.ctx, future.cancel = context.WithCancelCause(context.Background())
 
-	return future, nil
+	for _, opt := range opts {
+		opt(future)
+	}
+
+	return future
 }
@@ -1,8 +1,10 @@
 package future_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/andreiavrammsd/workexec/future"
 	"github.com/stretchr/testify/assert"
@@ -139,8 +141,9 @@ func (t *longRunningTask) Run(isCanceled func() bool) (interface{}, error) {
 }
 
 type eventsTask struct {
-	in  int
-	set interface{}
+	in    int
+	block bool
+	set   interface{}
 }
 
 func (e *eventsTask) OnSuccess(result interface{}) {
@@ -151,9 +154,169 @@ func (e *eventsTask) OnError(err error) {
 	e.set = err
 }
 
-func (e *eventsTask) Run(func() bool) (interface{}, error) {
+func (e *eventsTask) Run(isCanceled func() bool) (interface{}, error) {
+	for e.block && !isCanceled() {
+	}
 	if e.in == 0 {
 		return nil, errors.New("err")
 	}
 	return e.in, nil
 }
+
+func TestNewContext_WithNilTaskError(t *testing.T) {
+	taskFuture, err := future.NewContext(nil)
+	assert.Nil(t, taskFuture)
+	assert.Error(t, err)
+}
+
+func TestFuture_ContextTask(t *testing.T) {
+	taskFuture, err := future.NewContext(&contextTask{})
+	assert.NoError(t, err)
+
+	taskFuture.Run()
+	result, err := taskFuture.Result()
+
+	assert.Equal(t, "done", result)
+	assert.NoError(t, err)
+	assert.False(t, taskFuture.IsCanceled())
+	assert.NoError(t, taskFuture.Cause())
+}
+
+func TestFuture_CancelCause(t *testing.T) {
+	task := &contextTask{block: true}
+	taskFuture, err := future.NewContext(task)
+	assert.NoError(t, err)
+
+	cause := errors.New("not needed anymore")
+
+	taskFuture.Run()
+	taskFuture.CancelCause(cause)
+	taskFuture.Cancel()
+
+	result, err := taskFuture.Result()
+	assert.Nil(t, result)
+	assert.Equal(t, cause, err)
+	assert.True(t, taskFuture.IsCanceled())
+	assert.Equal(t, cause, taskFuture.Cause())
+	assert.True(t, task.canceled)
+}
+
+func TestFuture_OnCancelCause(t *testing.T) {
+	task := &causeTask{}
+	taskFuture, err := future.NewContext(task)
+	assert.NoError(t, err)
+
+	cause := errors.New("not needed anymore")
+
+	taskFuture.Run()
+	taskFuture.CancelCause(cause)
+	taskFuture.Wait()
+
+	assert.Equal(t, cause, task.cause)
+	assert.False(t, task.canceled)
+
+	task = &causeTask{}
+	taskFuture, err = future.NewContext(task, future.WithTimeout(time.Millisecond*10))
+	assert.NoError(t, err)
+
+	taskFuture.Run()
+	taskFuture.Wait()
+
+	assert.Equal(t, future.ErrDeadlineExceeded, task.cause)
+}
+
+// causeTask handles cancellation with and without cause, only the first must be called.
+type causeTask struct {
+	contextTask
+	cause error
+}
+
+func (t *causeTask) OnCancelCause(err error) {
+	t.cause = err
+}
+
+func (t *causeTask) RunContext(ctx context.Context) (interface{}, error) {
+	<-ctx.Done()
+	return nil, context.Cause(ctx)
+}
+
+func TestFuture_CancelPollingTaskCause(t *testing.T) {
+	taskFuture, err := future.New(&longRunningTask{})
+	assert.NoError(t, err)
+
+	taskFuture.Run()
+	taskFuture.Cancel()
+	taskFuture.Wait()
+
+	assert.Equal(t, future.ErrCanceled, taskFuture.Cause())
+}
+
+type contextTask struct {
+	block    bool
+	canceled bool
+}
+
+func (t *contextTask) OnCancel() {
+	t.canceled = true
+}
+
+func (t *contextTask) RunContext(ctx context.Context) (interface{}, error) {
+	if t.block {
+		<-ctx.Done()
+		return nil, context.Cause(ctx)
+	}
+	return "done", nil
+}
+
+func TestFuture_WithTimeout(t *testing.T) {
+	task := &contextTask{block: true}
+	taskFuture, err := future.NewContext(task, future.WithTimeout(time.Millisecond*10))
+	assert.NoError(t, err)
+
+	taskFuture.Run()
+	result, err := taskFuture.Result()
+
+	assert.Nil(t, result)
+	assert.Equal(t, future.ErrDeadlineExceeded, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, taskFuture.IsCanceled())
+	assert.True(t, task.canceled)
+}
+
+func TestFuture_DeadlineOfTaskIgnoringCancellation(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	taskFuture, err := future.New(&ignoringTask{release: release}, future.WithTimeout(time.Millisecond*10))
+	assert.NoError(t, err)
+
+	taskFuture.Run()
+	result, err := taskFuture.Result()
+
+	// The future is done at its deadline, while the task still runs
+	assert.Nil(t, result)
+	assert.Equal(t, future.ErrDeadlineExceeded, err)
+	assert.True(t, taskFuture.IsCanceled())
+}
+
+type ignoringTask struct {
+	release chan struct{}
+}
+
+func (t *ignoringTask) Run(func() bool) (interface{}, error) {
+	<-t.release
+	return "late", nil
+}
+
+func TestFuture_WithDeadline(t *testing.T) {
+	task := &eventsTask{in: 1, block: true}
+	taskFuture, err := future.New(task, future.WithDeadline(time.Now().Add(time.Millisecond*10)))
+	assert.NoError(t, err)
+
+	taskFuture.Run()
+	_, err = taskFuture.Result()
+
+	// Not cancelable tasks are notified with an error
+	assert.Equal(t, future.ErrDeadlineExceeded, err)
+	assert.Equal(t, future.ErrDeadlineExceeded, task.set)
+}
@@ -0,0 +1,27 @@
+package future
+
+import "github.com/andreiavrammsd/workexec/recovery"
+
+// PanicError is the error of a task which panicked.
+type PanicError = recovery.PanicError
+
+// PanicHandler is notified when a task panics, before the error is delivered.
+type PanicHandler = recovery.PanicHandler
+
+// WithPanicHandler sets a function to be notified when the task panics.
+func WithPanicHandler(handler PanicHandler) Option {
+	return func(f *Future) {
+		f.panicHandler = handler
+	}
+}
+
+// protect calls the task and converts a panic into a PanicError.
+func (f *Future) protect() (result interface{}, err error) {
+	defer func() {
+		if perr := recovery.Recovered(recover(), f.panicHandler); perr != nil {
+			result, err = nil, perr
+		}
+	}()
+
+	return f.call()
+}
@@ -0,0 +1,57 @@
+package future_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andreiavrammsd/workexec/future"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuture_Panic(t *testing.T) {
+	var handled *future.PanicError
+	task := &panicTask{value: "boom"}
+
+	taskFuture, err := future.New(task, future.WithPanicHandler(func(err *future.PanicError) {
+		handled = err
+	}))
+	assert.NoError(t, err)
+
+	taskFuture.Run()
+	result, err := taskFuture.Result()
+	assert.Nil(t, result)
+
+	var panicErr *future.PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.EqualError(t, err, "task panicked: boom")
+	assert.NoError(t, errors.Unwrap(err))
+
+	assert.Equal(t, panicErr, handled)
+	assert.Equal(t, err, task.onError)
+}
+
+func TestFuture_PanicWithError(t *testing.T) {
+	cause := errors.New("cause")
+	taskFuture, err := future.New(&panicTask{value: cause})
+	assert.NoError(t, err)
+
+	taskFuture.Run()
+	_, err = taskFuture.Result()
+
+	assert.True(t, errors.Is(err, cause))
+}
+
+type panicTask struct {
+	value   interface{}
+	onError error
+}
+
+func (t *panicTask) Run(func() bool) (interface{}, error) {
+	panic(t.value)
+}
+
+func (t *panicTask) OnError(err error) {
+	t.onError = err
+}
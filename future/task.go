@@ -1,5 +1,7 @@
 package future
 
+import "context"
+
 // Task is the interface a task must implement to be part of a job.
 type Task interface {
 	// Run is the function which must do the actual work.
@@ -8,6 +10,13 @@ type Task interface {
 	Run(func() bool) (interface{}, error)
 }
 
+// ContextTask is the interface a task must implement to be part of a future created with NewContext.
+type ContextTask interface {
+	// RunContext is the function which must do the actual work.
+	// The context is canceled when a cancellation has been requested, with the cancellation error as cause.
+	RunContext(context.Context) (interface{}, error)
+}
+
 // SuccessfulTask is the interface a task must implement to be notified
 // after is executed successfully. OnSuccess receives the task result.
 type SuccessfulTask interface {
@@ -24,3 +33,9 @@ type FailedTask interface {
 type CanceledTask interface {
 	OnCancel()
 }
+
+// CanceledCauseTask is the interface a task must implement to be notified when it's canceled
+// with the error it was canceled with. It is used instead of CanceledTask if a task implements both.
+type CanceledCauseTask interface {
+	OnCancelCause(error)
+}
@@ -0,0 +1,168 @@
+package future_with_generics
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNoFutures is the error of Race called without futures.
+var ErrNoFutures = errors.New("no futures passed")
+
+// AggregateError is the error of Any when all futures failed. Errors are in the order of the futures.
+type AggregateError struct {
+	Errors []error
+}
+
+func (e *AggregateError) Error() string {
+	if len(e.Errors) == 0 {
+		return "all futures failed: " + ErrNoFutures.Error()
+	}
+
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "all futures failed: " + strings.Join(messages, "; ")
+}
+
+func (e *AggregateError) Unwrap() []error {
+	return e.Errors
+}
+
+// Settled is the outcome of a future.
+type Settled[T any] struct {
+	Result   T
+	Err      error
+	Canceled bool
+}
+
+// All returns a running future with the results of the futures, in order.
+// It fails with the first error and cancels the other futures.
+// The futures are run if they were not. Canceling the returned future cancels all futures.
+func All[T any](futures ...*Future[T]) *Future[[]T] {
+	return aggregate(futures, func(outcomes <-chan outcome[T]) ([]T, error) {
+		results := make([]T, len(futures))
+		for range futures {
+			o := <-outcomes
+			if err := o.error(); err != nil {
+				cancelOthers(futures, o.index)
+				return nil, err
+			}
+			results[o.index] = o.result
+		}
+		return results, nil
+	})
+}
+
+// AllSettled returns a running future with the outcomes of all futures, in order. It does not fail.
+// The futures are run if they were not. Canceling the returned future cancels all futures.
+func AllSettled[T any](futures ...*Future[T]) *Future[[]Settled[T]] {
+	return aggregate(futures, func(outcomes <-chan outcome[T]) ([]Settled[T], error) {
+		settled := make([]Settled[T], len(futures))
+		for range futures {
+			o := <-outcomes
+			settled[o.index] = Settled[T]{Result: o.result, Err: o.err, Canceled: o.canceled}
+		}
+		return settled, nil
+	})
+}
+
+// Any returns a running future with the result of the first future which succeeds, and cancels the other futures.
+// It fails with an AggregateError if all futures fail.
+// The futures are run if they were not. Canceling the returned future cancels all futures.
+func Any[T any](futures ...*Future[T]) *Future[T] {
+	return aggregate(futures, func(outcomes <-chan outcome[T]) (T, error) {
+		errs := make([]error, len(futures))
+		for range futures {
+			o := <-outcomes
+			err := o.error()
+			if err == nil {
+				cancelOthers(futures, o.index)
+				return o.result, nil
+			}
+			errs[o.index] = err
+		}
+
+		var zero T
+		return zero, &AggregateError{Errors: errs}
+	})
+}
+
+// Race returns a running future with the outcome of the first future which is done, and cancels the other futures.
+// It fails with ErrNoFutures if no futures are passed.
+// The futures are run if they were not. Canceling the returned future cancels all futures.
+func Race[T any](futures ...*Future[T]) *Future[T] {
+	return aggregate(futures, func(outcomes <-chan outcome[T]) (T, error) {
+		if len(futures) == 0 {
+			var zero T
+			return zero, ErrNoFutures
+		}
+
+		o := <-outcomes
+		cancelOthers(futures, o.index)
+		return o.result, o.error()
+	})
+}
+
+// outcome of the future at index.
+type outcome[T any] struct {
+	index    int
+	result   T
+	err      error
+	canceled bool
+}
+
+// error returns the error of the future, ErrCanceled if it was canceled without an error.
+func (o outcome[T]) error() error {
+	if o.err == nil && o.canceled {
+		return ErrCanceled
+	}
+	return o.err
+}
+
+// aggregateTask runs futures and computes a result from their outcomes.
+type aggregateTask[T, U any] struct {
+	aggregate *Future[U]
+	futures   []*Future[T]
+	fn        func(<-chan outcome[T]) (U, error)
+}
+
+func (t *aggregateTask[T, U]) Run(func() bool) (U, error) {
+	for _, f := range t.futures {
+		if f == nil {
+			var zero U
+			return zero, errors.New("nil future passed")
+		}
+	}
+
+	outcomes := make(chan outcome[T], len(t.futures))
+	for i, f := range t.futures {
+		t.aggregate.follow(f.Cancel)
+		f.Run()
+
+		go func(i int, f *Future[T]) {
+			result, err := f.Result()
+			outcomes <- outcome[T]{index: i, result: result, err: err, canceled: f.IsCanceled()}
+		}(i, f)
+	}
+
+	return t.fn(outcomes)
+}
+
+// aggregate returns a running future which applies given function to the outcomes of futures, in order of completion.
+func aggregate[T, U any](futures []*Future[T], fn func(<-chan outcome[T]) (U, error)) *Future[U] {
+	task := &aggregateTask[T, U]{futures: futures, fn: fn}
+	task.aggregate, _ = New[U](task) // nolint:errcheck
+	task.aggregate.Run()
+
+	return task.aggregate
+}
+
+// cancelOthers cancels all futures except the one at index.
+func cancelOthers[T any](futures []*Future[T], index int) {
+	for i, f := range futures {
+		if i != index {
+			f.Cancel()
+		}
+	}
+}
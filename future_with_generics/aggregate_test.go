@@ -0,0 +1,191 @@
+package future_with_generics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/future_with_generics"
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	results, err := future_with_generics.All(
+		newValueFuture(t, 1, nil, time.Millisecond*20),
+		newValueFuture(t, 2, nil, 0),
+		newValueFuture(t, 3, nil, time.Millisecond*10),
+	).Result()
+	assert.Equal(t, []int{1, 2, 3}, results)
+	assert.NoError(t, err)
+
+	results, err = future_with_generics.All[int]().Result()
+	assert.Empty(t, results)
+	assert.NoError(t, err)
+}
+
+func TestAll_FailFast(t *testing.T) {
+	blocking := newValueFuture(t, 1, nil, time.Hour)
+
+	results, err := future_with_generics.All(blocking, newValueFuture(t, 0, errors.New("failed"), 0)).Result()
+	assert.Nil(t, results)
+	assert.EqualError(t, err, "failed")
+
+	blocking.Wait()
+	assert.True(t, blocking.IsCanceled())
+}
+
+func TestAllSettled(t *testing.T) {
+	canceled := newValueFuture(t, 1, nil, time.Hour)
+	canceled.Cancel()
+
+	settled, err := future_with_generics.AllSettled(
+		newValueFuture(t, 1, nil, 0),
+		newValueFuture(t, 0, errors.New("failed"), 0),
+		canceled,
+	).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, []future_with_generics.Settled[int]{
+		{Result: 1},
+		{Err: errors.New("failed")},
+		{Canceled: true},
+	}, settled)
+}
+
+func TestAny(t *testing.T) {
+	slow := newValueFuture(t, 1, nil, time.Hour)
+
+	result, err := future_with_generics.Any(
+		newValueFuture(t, 0, errors.New("failed"), 0),
+		slow,
+		newValueFuture(t, 3, nil, time.Millisecond*10),
+	).Result()
+	assert.Equal(t, 3, result)
+	assert.NoError(t, err)
+
+	slow.Wait()
+	assert.True(t, slow.IsCanceled())
+
+	first := errors.New("first")
+	second := errors.New("second")
+	_, err = future_with_generics.Any(
+		newValueFuture(t, 0, first, time.Millisecond*10),
+		newValueFuture(t, 0, second, 0),
+	).Result()
+
+	var aggregateErr *future_with_generics.AggregateError
+	assert.True(t, errors.As(err, &aggregateErr))
+	assert.Equal(t, []error{first, second}, aggregateErr.Errors)
+	assert.ErrorIs(t, err, second)
+	assert.EqualError(t, err, "all futures failed: first; second")
+}
+
+func TestRace(t *testing.T) {
+	slow := newValueFuture(t, 1, nil, time.Hour)
+
+	result, err := future_with_generics.Race(slow, newValueFuture(t, 0, errors.New("failed"), 0)).Result()
+	assert.Equal(t, 0, result)
+	assert.EqualError(t, err, "failed")
+
+	slow.Wait()
+	assert.True(t, slow.IsCanceled())
+
+	_, err = future_with_generics.Race[int]().Result()
+	assert.ErrorIs(t, err, future_with_generics.ErrNoFutures)
+}
+
+func TestAll_Cancel(t *testing.T) {
+	first := newValueFuture(t, 1, nil, time.Hour)
+	second := newValueFuture(t, 2, nil, time.Hour)
+
+	all := future_with_generics.All(first, second)
+	all.Cancel()
+
+	_, err := all.Result()
+	assert.ErrorIs(t, err, future_with_generics.ErrCanceled)
+	first.Wait()
+	second.Wait()
+	assert.True(t, first.IsCanceled())
+	assert.True(t, second.IsCanceled())
+}
+
+func TestFromJob(t *testing.T) {
+	j, err := job.New(&jobTask{value: 2})
+	assert.NoError(t, err)
+
+	fromJob, err := future_with_generics.FromJob[int](j, j.Run())
+	assert.NoError(t, err)
+
+	results, err := future_with_generics.All(fromJob, newValueFuture(t, 3, nil, 0)).Result()
+	assert.Equal(t, []int{2, 3}, results)
+	assert.NoError(t, err)
+
+	j, err = job.New(&jobTask{value: 2})
+	assert.NoError(t, err)
+	wrongType, err := future_with_generics.FromJob[string](j, j.Run())
+	assert.NoError(t, err)
+	_, err = wrongType.Result()
+	assert.Error(t, err)
+
+	_, err = future_with_generics.FromJob[int](nil, nil)
+	assert.Error(t, err)
+}
+
+func TestFromJob_Cancel(t *testing.T) {
+	j, err := job.NewContext(&blockingJobTask{})
+	assert.NoError(t, err)
+
+	fromJob, err := future_with_generics.FromJob[int](j, j.Run())
+	assert.NoError(t, err)
+
+	result, err := future_with_generics.Race(fromJob, newValueFuture(t, 1, nil, 0)).Result()
+	assert.Equal(t, 1, result)
+	assert.NoError(t, err)
+
+	_, err = fromJob.Result()
+	assert.ErrorIs(t, err, job.ErrCanceled)
+	assert.True(t, fromJob.IsCanceled())
+	assert.True(t, j.IsCanceled())
+}
+
+type valueTask struct {
+	value int
+	err   error
+	delay time.Duration
+}
+
+func (t *valueTask) Run(isCanceled func() bool) (int, error) {
+	for deadline := time.Now().Add(t.delay); time.Now().Before(deadline); {
+		if isCanceled() {
+			return 0, nil
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return t.value, t.err
+}
+
+func newValueFuture(t *testing.T, value int, err error, delay time.Duration) *future_with_generics.Future[int] {
+	t.Helper()
+
+	f, newErr := future_with_generics.New[int](&valueTask{value: value, err: err, delay: delay})
+	if newErr != nil {
+		t.Fatal(newErr)
+	}
+	return f
+}
+
+type jobTask struct {
+	value int
+}
+
+func (t *jobTask) Run(*job.Job) (interface{}, error) {
+	return t.value, nil
+}
+
+type blockingJobTask struct{}
+
+func (blockingJobTask) RunContext(ctx context.Context) (interface{}, error) {
+	<-ctx.Done()
+	return nil, context.Cause(ctx)
+}
@@ -0,0 +1,127 @@
+package future_with_generics
+
+import "errors"
+
+// ErrCanceled is the error of a combined future when the future it waited for was canceled without an error.
+var ErrCanceled = errors.New("canceled")
+
+// Map returns a running future with the result of given function applied to the result of the future.
+// The function is not called if the future failed, the error is passed on.
+// The future is run if it was not. Canceling the returned future cancels the future and the other way around.
+func Map[T, U any](f *Future[T], fn func(T) (U, error)) *Future[U] {
+	return Then(f, func(result T, err error) (U, error) {
+		if err != nil {
+			var zero U
+			return zero, err
+		}
+		return fn(result)
+	})
+}
+
+// Then returns a running future with the result of given function, called with the result and error of the future.
+// The future is run if it was not. Canceling the returned future cancels the future and the other way around.
+func Then[T, U any](f *Future[T], fn func(T, error) (U, error)) *Future[U] {
+	return combine(f, func(_ *Future[U], result T, err error) (U, error) {
+		return fn(result, err)
+	})
+}
+
+// Recover returns a running future which turns the error of the future into the result of given function.
+// The result of a successful future is passed on.
+// The future is run if it was not. Canceling the returned future cancels the future and the other way around.
+func Recover[T any](f *Future[T], fn func(error) (T, error)) *Future[T] {
+	return Then(f, func(result T, err error) (T, error) {
+		if err == nil {
+			return result, nil
+		}
+		return fn(err)
+	})
+}
+
+// FlatMap returns a running future with the result of the future returned by given function,
+// called with the result of the future. The function is not called if the future failed.
+// The futures are run if they were not. Canceling the returned future cancels both futures,
+// canceling any of them cancels the returned future.
+func FlatMap[T, U any](f *Future[T], fn func(T) (*Future[U], error)) *Future[U] {
+	return combine(f, func(combined *Future[U], result T, err error) (U, error) {
+		var zero U
+		if err != nil {
+			return zero, err
+		}
+
+		next, err := fn(result)
+		if err != nil {
+			return zero, err
+		}
+		if next == nil {
+			return zero, errors.New("nil future returned")
+		}
+
+		return await(combined, next)
+	})
+}
+
+// combineTask waits for a future and computes a result from its outcome.
+type combineTask[T, U any] struct {
+	combined *Future[U]
+	future   *Future[T]
+	fn       func(*Future[U], T, error) (U, error)
+}
+
+func (t *combineTask[T, U]) Run(func() bool) (U, error) {
+	result, err := await(t.combined, t.future)
+	if t.future.IsCanceled() {
+		var zero U
+		return zero, err
+	}
+
+	return t.fn(t.combined, result, err)
+}
+
+// combine returns a running future which applies given function to the outcome of a future.
+func combine[T, U any](f *Future[T], fn func(*Future[U], T, error) (U, error)) *Future[U] {
+	task := &combineTask[T, U]{future: f, fn: fn}
+	task.combined, _ = New[U](task) // nolint:errcheck
+	task.combined.Run()
+
+	return task.combined
+}
+
+// await runs a future and waits for it. Each of the combined future and the future
+// is canceled when the other one is.
+func await[T, U any](combined *Future[U], f *Future[T]) (T, error) {
+	combined.follow(f.Cancel)
+
+	f.Run()
+	result, err := f.Result()
+
+	if f.IsCanceled() {
+		combined.markCanceled()
+
+		if err == nil {
+			err = ErrCanceled
+		}
+	}
+
+	return result, err
+}
+
+// follow cancels given function when the future is canceled, now if it already was.
+func (f *Future[T]) follow(cancel func()) {
+	f.lock.Lock()
+	if !f.canceled {
+		f.upstream = append(f.upstream, cancel)
+		f.lock.Unlock()
+		return
+	}
+	f.lock.Unlock()
+
+	cancel()
+}
+
+// markCanceled flags the future as canceled without canceling the futures it follows.
+func (f *Future[T]) markCanceled() {
+	f.lock.Lock()
+	f.canceled = true
+	f.lock.Unlock()
+}
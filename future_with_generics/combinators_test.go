@@ -0,0 +1,122 @@
+package future_with_generics_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/andreiavrammsd/workexec/future_with_generics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	taskFuture, err := future_with_generics.New[float64](&divideTask{a: 4, b: 2})
+	assert.NoError(t, err)
+
+	mapped := future_with_generics.Map(taskFuture, func(result float64) (string, error) {
+		return strconv.FormatFloat(result, 'f', 1, 64), nil
+	})
+	result, err := mapped.Result()
+	assert.Equal(t, "2.0", result)
+	assert.NoError(t, err)
+
+	failed, err := future_with_generics.New[float64](&divideTask{a: 4, b: 0})
+	assert.NoError(t, err)
+
+	called := false
+	mapped = future_with_generics.Map(failed, func(result float64) (string, error) {
+		called = true
+		return "", nil
+	})
+	result, err = mapped.Result()
+	assert.Equal(t, "", result)
+	assert.EqualError(t, err, "division by zero")
+	assert.False(t, called)
+}
+
+func TestThen(t *testing.T) {
+	taskFuture, err := future_with_generics.New[float64](&divideTask{a: 4, b: 0})
+	assert.NoError(t, err)
+
+	then := future_with_generics.Then(taskFuture, func(result float64, err error) (string, error) {
+		return err.Error(), nil
+	})
+	result, err := then.Result()
+	assert.Equal(t, "division by zero", result)
+	assert.NoError(t, err)
+}
+
+func TestRecover(t *testing.T) {
+	failed, err := future_with_generics.New[float64](&divideTask{a: 4, b: 0})
+	assert.NoError(t, err)
+
+	recovered := future_with_generics.Recover(failed, func(error) (float64, error) {
+		return -1, nil
+	})
+	result, err := recovered.Result()
+	assert.Equal(t, -1.0, result)
+	assert.NoError(t, err)
+
+	succeeded, err := future_with_generics.New[float64](&divideTask{a: 4, b: 2})
+	assert.NoError(t, err)
+
+	recovered = future_with_generics.Recover(succeeded, func(error) (float64, error) {
+		return -1, nil
+	})
+	result, err = recovered.Result()
+	assert.Equal(t, 2.0, result)
+	assert.NoError(t, err)
+}
+
+func TestFlatMap(t *testing.T) {
+	taskFuture, err := future_with_generics.New[float64](&divideTask{a: 8, b: 2})
+	assert.NoError(t, err)
+
+	half := func(result float64) (*future_with_generics.Future[float64], error) {
+		return future_with_generics.New[float64](&divideTask{a: int(result), b: 2})
+	}
+	chained := future_with_generics.FlatMap(taskFuture, half)
+	result, err := chained.Result()
+	assert.Equal(t, 2.0, result)
+	assert.NoError(t, err)
+
+	chained = future_with_generics.FlatMap(taskFuture, func(float64) (*future_with_generics.Future[float64], error) {
+		return nil, errors.New("no future")
+	})
+	_, err = chained.Result()
+	assert.EqualError(t, err, "no future")
+}
+
+func TestMap_CancelUpstream(t *testing.T) {
+	task := &longRunningTask{}
+	taskFuture, err := future_with_generics.New[int](task)
+	assert.NoError(t, err)
+
+	mapped := future_with_generics.Map(taskFuture, func(result int) (int, error) {
+		return result, nil
+	})
+	mapped.Cancel()
+
+	_, err = mapped.Result()
+	assert.ErrorIs(t, err, future_with_generics.ErrCanceled)
+	assert.True(t, mapped.IsCanceled())
+	assert.True(t, taskFuture.IsCanceled())
+}
+
+func TestFlatMap_CancelDownstream(t *testing.T) {
+	taskFuture, err := future_with_generics.New[float64](&divideTask{a: 4, b: 2})
+	assert.NoError(t, err)
+
+	inner, err := future_with_generics.New[int](&longRunningTask{})
+	assert.NoError(t, err)
+
+	chained := future_with_generics.FlatMap(taskFuture, func(float64) (*future_with_generics.Future[int], error) {
+		return inner, nil
+	})
+	inner.Cancel()
+
+	result, err := chained.Result()
+	assert.Equal(t, 0, result)
+	assert.ErrorIs(t, err, future_with_generics.ErrCanceled)
+	assert.True(t, chained.IsCanceled())
+}
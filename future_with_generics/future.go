@@ -4,6 +4,8 @@ package future_with_generics
 import (
 	"errors"
 	"sync"
+
+	"github.com/andreiavrammsd/workexec/recovery"
 )
 
 // Future represents a task which executes async work.
@@ -15,6 +17,9 @@ type Future[T any] struct {
 	canceled bool
 	on       bool
 	lock     sync.RWMutex
+	// upstream are canceled when the future is canceled
+	upstream []func()
+	options  options
 }
 
 // Run executes the Task async.
@@ -60,7 +65,13 @@ func (f *Future[T]) Result() (T, error) {
 func (f *Future[T]) Cancel() {
 	f.lock.Lock()
 	f.canceled = true
+	upstream := f.upstream
+	f.upstream = nil
 	f.lock.Unlock()
+
+	for _, cancel := range upstream {
+		cancel()
+	}
 }
 
 // IsCanceled returns true if task was canceled.
@@ -73,7 +84,7 @@ func (f *Future[T]) IsCanceled() bool {
 func (f *Future[T]) run() {
 	defer close(f.done)
 
-	f.result, f.err = f.task.Run(f.IsCanceled)
+	f.result, f.err = f.call()
 
 	if task, ok := f.task.(CanceledTask); ok {
 		f.lock.RLock()
@@ -98,8 +109,20 @@ func (f *Future[T]) run() {
 	}
 }
 
+// call runs the task and converts a panic into a PanicError.
+func (f *Future[T]) call() (result T, err error) {
+	defer func() {
+		if perr := recovery.Recovered(recover(), f.options.panicHandler); perr != nil {
+			var zero T
+			result, err = zero, perr
+		}
+	}()
+
+	return f.task.Run(f.IsCanceled)
+}
+
 // New creates a new future with a given task.
-func New[T any](task Task[T]) (*Future[T], error) {
+func New[T any](task Task[T], opts ...Option) (*Future[T], error) {
 	if task == nil {
 		return nil, errors.New("nil task passed")
 	}
@@ -108,6 +131,9 @@ func New[T any](task Task[T]) (*Future[T], error) {
 		task: task,
 		done: make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(&future.options)
+	}
 
 	return future, nil
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/andreiavrammsd/workexec/future_with_generics"
+	"github.com/andreiavrammsd/workexec/recovery"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -159,3 +160,29 @@ func (e *eventsTask) Run(func() bool) (int, error) {
 	}
 	return e.in, nil
 }
+
+func TestFuture_Panic(t *testing.T) {
+	var handled *future_with_generics.PanicError
+	taskFuture, err := future_with_generics.New[int](&panicTask{}, future_with_generics.WithPanicHandler(
+		func(err *future_with_generics.PanicError) {
+			handled = err
+		},
+	))
+	assert.NoError(t, err)
+
+	taskFuture.Run()
+	result, err := taskFuture.Result()
+
+	assert.Equal(t, 0, result)
+	var panicErr *recovery.PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.Same(t, panicErr, handled)
+}
+
+type panicTask struct{}
+
+func (panicTask) Run(func() bool) (int, error) {
+	panic("boom")
+}
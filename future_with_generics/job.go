@@ -0,0 +1,53 @@
+package future_with_generics
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+// FromJob adapts the future of a running job, so it can be combined and aggregated with other futures.
+// The result of the job must be of type T. Canceling the returned future cancels the job.
+func FromJob[T any](j *job.Job, f *job.Future) (*Future[T], error) {
+	if j == nil || f == nil {
+		return nil, errors.New("nil job or job future passed")
+	}
+
+	task := &jobTask[T]{job: j, future: f}
+	task.adapter, _ = New[T](task) // nolint:errcheck
+	task.adapter.follow(func() {
+		j.Cancel(nil)
+	})
+	task.adapter.Run()
+
+	return task.adapter, nil
+}
+
+// jobTask waits for a job future.
+type jobTask[T any] struct {
+	adapter *Future[T]
+	job     *job.Job
+	future  *job.Future
+}
+
+func (t *jobTask[T]) Run(func() bool) (T, error) {
+	var zero T
+
+	result := t.future.Result()
+	err := t.future.Error()
+
+	if t.future.IsCanceled() {
+		t.adapter.markCanceled()
+	}
+
+	if err != nil || result == nil {
+		return zero, err
+	}
+
+	typed, ok := result.(T)
+	if !ok {
+		return zero, fmt.Errorf("job %s result is %T, not %T", t.job.ID(), result, zero)
+	}
+	return typed, nil
+}
@@ -0,0 +1,23 @@
+package future_with_generics
+
+import "github.com/andreiavrammsd/workexec/recovery"
+
+// PanicError is the error of a task which panicked.
+type PanicError = recovery.PanicError
+
+// PanicHandler is notified when a task panics, before the error is delivered.
+type PanicHandler = recovery.PanicHandler
+
+// Option allows setup of a future.
+type Option func(*options)
+
+type options struct {
+	panicHandler PanicHandler
+}
+
+// WithPanicHandler sets a function to be notified when the task panics.
+func WithPanicHandler(handler PanicHandler) Option {
+	return func(o *options) {
+		o.panicHandler = handler
+	}
+}
@@ -2,6 +2,8 @@ package futurereflect
 
 import (
 	"reflect"
+
+	"github.com/andreiavrammsd/workexec/recovery"
 )
 
 // Future represents a function which executes async work.
@@ -12,6 +14,7 @@ type Future struct {
 	wait          chan struct{}
 	result        interface{}
 	err           error
+	panicHandler  PanicHandler
 }
 
 // Wait blocks until function is done.
@@ -34,7 +37,11 @@ func (f *Future) run() {
 		values[i] = reflect.ValueOf(f.args[i])
 	}
 
-	ret := f.functionValue.Call(values)
+	ret, err := f.call(values)
+	if err != nil {
+		f.err = err
+		return
+	}
 
 	if len(ret) == 0 {
 		return
@@ -48,11 +55,22 @@ func (f *Future) run() {
 	}
 }
 
+// call calls the function and converts a panic into a PanicError.
+func (f *Future) call(values []reflect.Value) (ret []reflect.Value, err error) {
+	defer func() {
+		if perr := recovery.Recovered(recover(), f.panicHandler); perr != nil {
+			err = perr
+		}
+	}()
+
+	return f.functionValue.Call(values), nil
+}
+
 // Callable is the function to call in order to start running the passed function.
 type Callable func(args ...interface{}) *Future
 
-// New returns a Callable.
-func New(function interface{}) Callable {
+// New returns a Callable. Given options are applied to every future of the Callable.
+func New(function interface{}, opts ...Option) Callable {
 	if function == nil {
 		return nil
 	}
@@ -74,6 +92,9 @@ func New(function interface{}) Callable {
 			args:          args,
 			wait:          make(chan struct{}),
 		}
+		for _, opt := range opts {
+			opt(future)
+		}
 
 		go future.run()
 
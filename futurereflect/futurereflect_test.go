@@ -1,10 +1,12 @@
 package futurereflect_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
 	"github.com/andreiavrammsd/workexec/futurereflect"
+	"github.com/andreiavrammsd/workexec/recovery"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -48,3 +50,22 @@ func (l *lines) str(lines []string) {
 func (l *lines) delete() {
 	l.out = ""
 }
+
+func TestPanic(t *testing.T) {
+	boom := errors.New("boom")
+	fail := func() int {
+		panic(boom)
+	}
+
+	var handled *futurereflect.PanicError
+	result, err := futurereflect.New(fail, futurereflect.WithPanicHandler(func(err *futurereflect.PanicError) {
+		handled = err
+	}))().Result()
+	assert.Nil(t, result)
+
+	var panicErr *recovery.PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.True(t, errors.Is(err, boom))
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.Same(t, panicErr, handled)
+}
@@ -0,0 +1,19 @@
+package futurereflect
+
+import "github.com/andreiavrammsd/workexec/recovery"
+
+// PanicError is the error of a function which panicked.
+type PanicError = recovery.PanicError
+
+// PanicHandler is notified when a function panics, before the error is delivered.
+type PanicHandler = recovery.PanicHandler
+
+// Option allows setup of the futures of a Callable.
+type Option func(*Future)
+
+// WithPanicHandler sets a function to be notified when the function panics.
+func WithPanicHandler(handler PanicHandler) Option {
+	return func(f *Future) {
+		f.panicHandler = handler
+	}
+}
@@ -0,0 +1,51 @@
+// Package deadline bounds how long a task runs, for the futures and jobs of the library.
+package deadline
+
+import (
+	"sync"
+	"time"
+)
+
+// From returns the earliest of given deadline and the timeout since given start.
+// A zero deadline or timeout is not set. It returns false if none is set.
+func From(start, deadline time.Time, timeout time.Duration) (time.Time, bool) {
+	if timeout > 0 {
+		if at := start.Add(timeout); deadline.IsZero() || at.Before(deadline) {
+			deadline = at
+		}
+	}
+
+	return deadline, !deadline.IsZero()
+}
+
+// Expiry decides once between a task which returned and its deadline,
+// so a deadline reached right after the task returned does not replace its result.
+type Expiry struct {
+	lock     sync.Mutex
+	returned bool
+	expired  bool
+}
+
+// Expire marks the deadline as reached. It returns false if the task returned first.
+func (e *Expiry) Expire() bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.returned {
+		return false
+	}
+	e.expired = true
+	return true
+}
+
+// Return marks the task as returned. It returns false if the deadline was reached first.
+func (e *Expiry) Return() bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.expired {
+		return false
+	}
+	e.returned = true
+	return true
+}
@@ -0,0 +1,36 @@
+package deadline_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/internal/deadline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom(t *testing.T) {
+	start := time.Now()
+
+	_, ok := deadline.From(start, time.Time{}, 0)
+	assert.False(t, ok)
+
+	at, ok := deadline.From(start, time.Time{}, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Second), at)
+
+	at, _ = deadline.From(start, start.Add(time.Millisecond), time.Second)
+	assert.Equal(t, start.Add(time.Millisecond), at)
+
+	at, _ = deadline.From(start, start.Add(time.Hour), time.Second)
+	assert.Equal(t, start.Add(time.Second), at)
+}
+
+func TestExpiry(t *testing.T) {
+	returned := &deadline.Expiry{}
+	assert.True(t, returned.Return())
+	assert.False(t, returned.Expire())
+
+	expired := &deadline.Expiry{}
+	assert.True(t, expired.Expire())
+	assert.False(t, expired.Return())
+}
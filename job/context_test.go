@@ -0,0 +1,115 @@
+package job_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewContext(t *testing.T) {
+	taskJob, err := job.NewContext(nil)
+	assert.Nil(t, taskJob)
+	assert.Error(t, err)
+
+	taskJob, err = job.NewContext(&contextTask{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, job.ID(""), taskJob.ID())
+
+	future := taskJob.Run()
+	assert.Equal(t, "done", future.Result())
+	assert.NoError(t, future.Error())
+	assert.False(t, future.IsCanceled())
+	assert.NoError(t, taskJob.Cause())
+}
+
+func TestJob_CancelContextTask(t *testing.T) {
+	task := &contextTask{block: true}
+	taskJob, err := job.NewContext(task)
+	assert.NoError(t, err)
+
+	cause := errors.New("shutting down")
+
+	future := taskJob.Run()
+	taskJob.Cancel(cause)
+	taskJob.Cancel(errors.New("ignored"))
+	future.Wait()
+
+	assert.True(t, future.IsCanceled())
+	assert.Equal(t, cause, future.Error())
+	assert.Equal(t, cause, taskJob.Cause())
+	assert.Equal(t, cause, context.Cause(taskJob.Context()))
+	assert.Equal(t, cause, task.onCancel)
+}
+
+func TestJob_RunContext(t *testing.T) {
+	task := &contextTask{block: true}
+	taskJob, err := job.NewContext(task)
+	assert.NoError(t, err)
+
+	cause := errors.New("parent canceled")
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	future := taskJob.RunContext(ctx)
+	cancel(cause)
+	future.Wait()
+
+	assert.True(t, future.IsCanceled())
+	assert.Equal(t, cause, task.onCancel)
+}
+
+func TestJob_RunContextWithPollingTask(t *testing.T) {
+	task := &pollingTask{}
+	taskJob, err := job.New(task)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	future := taskJob.RunContext(ctx)
+	cancel()
+	future.Wait()
+
+	assert.True(t, future.IsCanceled())
+	assert.Equal(t, context.Canceled, task.onCancel)
+}
+
+func TestJob_CancelWithNilError(t *testing.T) {
+	taskJob, err := job.NewContext(&contextTask{})
+	assert.NoError(t, err)
+
+	taskJob.Cancel(nil)
+	assert.Equal(t, job.ErrCanceled, taskJob.Cause())
+}
+
+type contextTask struct {
+	block    bool
+	onCancel error
+}
+
+func (t *contextTask) RunContext(ctx context.Context) (interface{}, error) {
+	if t.block {
+		<-ctx.Done()
+		return nil, context.Cause(ctx)
+	}
+	return "done", nil
+}
+
+func (t *contextTask) OnCancel(err error) {
+	t.onCancel = err
+}
+
+type pollingTask struct {
+	onCancel error
+}
+
+func (t *pollingTask) Run(j *job.Job) (interface{}, error) {
+	for !j.IsCanceled() {
+	}
+	return nil, nil
+}
+
+func (t *pollingTask) OnCancel(err error) {
+	t.onCancel = err
+}
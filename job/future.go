@@ -6,6 +6,8 @@ type Future struct {
 	result   interface{}
 	err      error
 	canceled bool
+	attempts int
+	errors   []error
 }
 
 // Wait blocks until job is done.
@@ -13,6 +15,11 @@ func (f *Future) Wait() {
 	<-f.done
 }
 
+// Done returns a channel which is closed when job is done.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
 // Result returns job result. Blocks until job is done.
 func (f *Future) Result() interface{} {
 	<-f.done
@@ -28,3 +35,15 @@ func (f *Future) Error() error {
 func (f *Future) IsCanceled() bool {
 	return f.canceled
 }
+
+// Attempts returns the number of times the task was run. Blocks until job is done.
+func (f *Future) Attempts() int {
+	<-f.done
+	return f.attempts
+}
+
+// Errors returns the errors of all failed attempts, in order. Blocks until job is done.
+func (f *Future) Errors() []error {
+	<-f.done
+	return f.errors
+}
@@ -5,75 +5,211 @@
 package job
 
 import (
+	"context"
 	"errors"
-	"sync"
+	"fmt"
+	"time"
 
+	"github.com/andreiavrammsd/workexec/internal/deadline"
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrCanceled is the cause of a job canceled with a nil error.
+	ErrCanceled = errors.New("canceled")
+	// ErrDeadlineExceeded is the error of a job which did not finish before its deadline.
+	ErrDeadlineExceeded = fmt.Errorf("job %w", context.DeadlineExceeded)
+)
+
 // ID of a job.
 type ID string
 
 // Job contains a Task.
 type Job struct {
-	id     uuid.UUID
-	task   Task
-	cancel error
-	lock   sync.RWMutex
+	id       ID
+	key      string
+	task     interface{}
+	run      func(*Job) (interface{}, error)
+	retry    *RetryPolicy
+	timeout  time.Duration
+	deadline time.Time
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+
+	panicHandler PanicHandler
+}
+
+// Option allows setup of a job.
+type Option func(*Job)
+
+// WithRetry runs the task again when it fails, following given policy.
+// OnError is called only after the last attempt failed.
+func WithRetry(policy RetryPolicy) Option {
+	return func(j *Job) {
+		j.retry = &policy
+	}
+}
+
+// WithTimeout cancels the job if it is still running after given duration since it was started.
+func WithTimeout(timeout time.Duration) Option {
+	return func(j *Job) {
+		j.timeout = timeout
+	}
+}
+
+// WithDeadline cancels the job if it is still running at given time.
+func WithDeadline(deadline time.Time) Option {
+	return func(j *Job) {
+		j.deadline = deadline
+	}
+}
+
+// WithID sets the identifier of the job, instead of a generated one.
+// It allows restoring a job which was persisted or sent to another process.
+func WithID(id ID) Option {
+	return func(j *Job) {
+		j.id = id
+	}
+}
+
+// WithIdempotencyKey sets a key identifying the work of the job, so a runner does not run it twice.
+// Unlike the ID, the key is chosen by the client, e.g. from a request, and is the same when the job is enqueued again.
+func WithIdempotencyKey(key string) Option {
+	return func(j *Job) {
+		j.key = key
+	}
 }
 
 // ID returns the job unique identifier.
 func (j *Job) ID() ID {
-	return ID(j.id.String())
+	return j.id
+}
+
+// IdempotencyKey returns the key set by WithIdempotencyKey, empty if not set.
+func (j *Job) IdempotencyKey() string {
+	return j.key
+}
+
+// Task returns the task of the job.
+func (j *Job) Task() interface{} {
+	return j.task
 }
 
 // Run starts executing the job task and returns a Future.
 func (j *Job) Run() *Future {
+	return j.RunContext(context.Background())
+}
+
+// RunContext starts executing the job task and returns a Future.
+// When given context is done, the job is canceled with the cause of the context.
+// A deadline of the context is a deadline of the job.
+func (j *Job) RunContext(ctx context.Context) *Future {
 	future := &Future{
 		done: make(chan struct{}),
 	}
 
 	go func() {
 		defer close(future.done)
-		future.result, future.err = j.run()
+
+		if at, ok := deadline.From(time.Now(), j.deadline, j.timeout); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, at)
+			defer cancel()
+		}
+
+		expiry := &deadline.Expiry{}
+		stop := context.AfterFunc(ctx, func() {
+			cause := context.Cause(ctx)
+
+			// A deadline cancels any task
+			if errors.Is(cause, context.DeadlineExceeded) {
+				if expiry.Expire() {
+					j.cancel(ErrDeadlineExceeded)
+				}
+				return
+			}
+
+			j.Cancel(cause)
+		})
+		defer stop()
+
+		future.result, future.err = j.execute(future, expiry)
 		future.canceled = j.IsCanceled()
 	}()
 
 	return future
 }
 
-// Cancel asks the job to stop.
+// Cancel asks the job to stop. The context of the job is canceled with given error as cause.
+// Only the first cancellation is taken into account.
+// Tasks which are neither CancelableTask nor ContextTask cannot be canceled.
 func (j *Job) Cancel(err error) {
-	if _, ok := j.task.(CancelableTask); !ok {
+	if !j.isCancelable() {
 		return
 	}
 
 	if err == nil {
-		err = errors.New("canceled")
+		err = ErrCanceled
 	}
 
-	j.lock.Lock()
-	j.cancel = err
-	j.lock.Unlock()
+	j.cancel(err)
+}
+
+// Clone returns a job with the same ID, task and options, which was not canceled.
+// It allows running a job again.
+func (j *Job) Clone() *Job {
+	clone := &Job{
+		id:       j.id,
+		key:      j.key,
+		task:     j.task,
+		run:      j.run,
+		retry:    j.retry,
+		timeout:  j.timeout,
+		deadline: j.deadline,
+
+		panicHandler: j.panicHandler,
+	}
+	clone.ctx, clone.cancel = context.WithCancelCause(context.Background())
+
+	return clone
 }
 
 // IsCanceled returns true if job was canceled.
 func (j *Job) IsCanceled() bool {
-	j.lock.RLock()
-	defer j.lock.RUnlock()
-	return j.cancel != nil
+	return j.ctx.Err() != nil
+}
+
+// Context returns the context of the job, which is done when the job is canceled.
+func (j *Job) Context() context.Context {
+	return j.ctx
 }
 
-func (j *Job) run() (result interface{}, err error) {
-	result, err = j.task.Run(j)
+// Cause returns the error the job was canceled with, or nil if not canceled.
+func (j *Job) Cause() error {
+	return context.Cause(j.ctx)
+}
 
-	if task, ok := j.task.(CancelableTask); ok {
-		j.lock.RLock()
-		cancel := j.cancel
-		j.lock.RUnlock()
+func (j *Job) isCancelable() bool {
+	if _, ok := j.task.(CancelableTask); ok {
+		return true
+	}
+	_, ok := j.task.(ContextTask)
+	return ok
+}
 
-		if cancel != nil {
-			task.OnCancel(j.cancel)
+// execute runs the task and reports the outcome to it. The future of a job is done only when the task
+// returned, even after the deadline, so a runner can tell the tasks which ignored it.
+func (j *Job) execute(future *Future, expiry *deadline.Expiry) (result interface{}, err error) {
+	result, err = j.attempt(future)
+	expiry.Return()
+
+	if errors.Is(j.Cause(), ErrDeadlineExceeded) {
+		return j.expire()
+	}
+
+	if task, ok := j.task.(CancelableTask); ok {
+		if cause := j.Cause(); cause != nil {
+			task.OnCancel(cause)
 			return
 		}
 	}
@@ -93,16 +229,89 @@ func (j *Job) run() (result interface{}, err error) {
 	return
 }
 
+// expire reports a deadline exceeded as a cancellation if the task is cancelable, else as an error.
+func (j *Job) expire() (interface{}, error) {
+	if task, ok := j.task.(CancelableTask); ok {
+		task.OnCancel(ErrDeadlineExceeded)
+	} else if task, ok := j.task.(FailedTask); ok {
+		task.OnError(ErrDeadlineExceeded)
+	}
+
+	return nil, ErrDeadlineExceeded
+}
+
+// attempt runs the task until it succeeds, it is canceled or the retry policy gives up.
+func (j *Job) attempt(future *Future) (result interface{}, err error) {
+	start := time.Now()
+	var delay time.Duration
+
+	for {
+		result, err = j.protect()
+		future.attempts++
+
+		if err == nil || j.IsCanceled() {
+			return
+		}
+
+		future.errors = append(future.errors, err)
+
+		if j.retry == nil {
+			return
+		}
+
+		var retry bool
+		delay, retry = j.retry.next(future.attempts, err, delay, time.Since(start))
+		if !retry {
+			return
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-j.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
 // New creates a new job with a given task.
-func New(task Task) (*Job, error) {
+func New(task Task, opts ...Option) (*Job, error) {
 	if task == nil {
 		return nil, errors.New("nil task passed to job")
 	}
 
+	// Polling tasks are adapted to the context of the job, they check it with IsCanceled.
+	job := newJob(task, opts)
+	job.run = task.Run
+
+	return job, nil
+}
+
+// NewContext creates a new job with a given task which is canceled through its context.
+func NewContext(task ContextTask, opts ...Option) (*Job, error) {
+	if task == nil {
+		return nil, errors.New("nil task passed to job")
+	}
+
+	job := newJob(task, opts)
+	job.run = func(j *Job) (interface{}, error) {
+		return task.RunContext(j.ctx)
+	}
+
+	return job, nil
+}
+
+func newJob(task interface{}, opts []Option) *Job {
 	job := &Job{
 		task: task,
-		id:   uuid.New(),
+		id:   ID(uuid.New().String()),
 	}
+	job.ctx, job.cancel = context.WithCancelCause(context.Background())
 
-	return job, nil
+	for _, opt := range opts {
+		opt(job)
+	}
+
+	return job
 }
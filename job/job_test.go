@@ -54,6 +54,34 @@ func TestJob_CancelWithNotCancelableTask(t *testing.T) {
 	}
 }
 
+func TestJob_Clone(t *testing.T) {
+	taskJob, err := job.New(&task{in: 1}, job.WithIdempotencyKey("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taskJob.Cancel(nil)
+	future := taskJob.Run()
+	future.Wait()
+	if !future.IsCanceled() {
+		t.Error("expected job to be canceled")
+	}
+
+	clone := taskJob.Clone()
+	if clone.ID() != taskJob.ID() {
+		t.Error("expected same ID")
+	}
+	if clone.IdempotencyKey() != "key" {
+		t.Error("expected same idempotency key")
+	}
+	if clone.IsCanceled() {
+		t.Error("expected clone to not be canceled")
+	}
+	if clone.Run().Result() != 2 {
+		t.Error("expected clone to run")
+	}
+}
+
 type normalTask struct {
 }
 
@@ -0,0 +1,28 @@
+package job
+
+import "github.com/andreiavrammsd/workexec/recovery"
+
+// PanicError is the error of a task which panicked.
+type PanicError = recovery.PanicError
+
+// PanicHandler is notified when a task panics, before the error is delivered.
+type PanicHandler = recovery.PanicHandler
+
+// WithPanicHandler sets a function to be notified when the task panics.
+// A panic is a failed attempt, which can be retried.
+func WithPanicHandler(handler PanicHandler) Option {
+	return func(j *Job) {
+		j.panicHandler = handler
+	}
+}
+
+// protect runs the task and converts a panic into a PanicError.
+func (j *Job) protect() (result interface{}, err error) {
+	defer func() {
+		if perr := recovery.Recovered(recover(), j.panicHandler); perr != nil {
+			result, err = nil, perr
+		}
+	}()
+
+	return j.run(j)
+}
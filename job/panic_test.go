@@ -0,0 +1,61 @@
+package job_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob_Panic(t *testing.T) {
+	var handled []*job.PanicError
+	task := &panicTask{panics: 1}
+
+	taskJob, err := job.New(task, job.WithPanicHandler(func(err *job.PanicError) {
+		handled = append(handled, err)
+	}))
+	assert.NoError(t, err)
+
+	future := taskJob.Run()
+	assert.Nil(t, future.Result())
+
+	var panicErr *job.PanicError
+	assert.True(t, errors.As(future.Error(), &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+
+	assert.Len(t, handled, 1)
+	assert.Equal(t, future.Error(), task.onError)
+}
+
+func TestJob_PanicRetried(t *testing.T) {
+	task := &panicTask{panics: 2}
+	taskJob, err := job.New(task, job.WithRetry(job.RetryPolicy{MaxAttempts: 3}))
+	assert.NoError(t, err)
+
+	future := taskJob.Run()
+
+	assert.Equal(t, "ok", future.Result())
+	assert.NoError(t, future.Error())
+	assert.Equal(t, 3, future.Attempts())
+	assert.Len(t, future.Errors(), 2)
+}
+
+type panicTask struct {
+	panics  int
+	runs    int
+	onError error
+}
+
+func (t *panicTask) Run(*job.Job) (interface{}, error) {
+	t.runs++
+	if t.runs <= t.panics {
+		panic("boom")
+	}
+	return "ok", nil
+}
+
+func (t *panicTask) OnError(err error) {
+	t.onError = err
+}
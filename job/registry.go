@@ -0,0 +1,255 @@
+package job
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// defaultRegistry is used by the package level Register, Marshal and Unmarshal.
+var defaultRegistry = NewRegistry() // nolint:gochecknoglobals
+
+// UnknownTaskError is returned for a task type which is not registered.
+type UnknownTaskError struct {
+	// Name is the registered name when unmarshaling, the Go type when marshaling.
+	Name string
+}
+
+func (e *UnknownTaskError) Error() string {
+	return fmt.Sprintf("task %q is not registered", e.Name)
+}
+
+// Codec converts a task to bytes and back.
+type Codec interface {
+	Encode(task interface{}) ([]byte, error)
+	// Decode sets the task, which is a pointer returned by the task constructor.
+	Decode(data []byte, task interface{}) error
+}
+
+// JSONCodec encodes the exported fields of a task as JSON. It is the default codec.
+type JSONCodec struct{}
+
+// Encode returns the JSON encoding of the task.
+func (JSONCodec) Encode(task interface{}) ([]byte, error) {
+	return json.Marshal(task)
+}
+
+// Decode parses JSON into the task.
+func (JSONCodec) Decode(data []byte, task interface{}) error {
+	return json.Unmarshal(data, task)
+}
+
+// GobCodec encodes the exported fields of a task with encoding/gob.
+type GobCodec struct{}
+
+// Encode returns the gob encoding of the task.
+func (GobCodec) Encode(task interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(task); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode parses gob data into the task.
+func (GobCodec) Decode(data []byte, task interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(task)
+}
+
+// Migration converts the payload of a task from an older version to the current one.
+type Migration func(version int, payload []byte) ([]byte, error)
+
+// TaskOption allows setup of a registered task type.
+type TaskOption func(*taskType)
+
+// WithCodec sets the codec of the task payload.
+func WithCodec(codec Codec) TaskOption {
+	return func(t *taskType) {
+		t.codec = codec
+	}
+}
+
+// WithVersion sets the version of the task payload, which is saved with it. Payloads of older versions
+// are passed to given migration before decoding. Payloads of newer versions cannot be decoded.
+func WithVersion(version int, migrate Migration) TaskOption {
+	return func(t *taskType) {
+		t.version = version
+		t.migrate = migrate
+	}
+}
+
+// taskType is a registered task.
+type taskType struct {
+	name        string
+	constructor func() interface{}
+	codec       Codec
+	version     int
+	migrate     Migration
+}
+
+// envelope is a marshaled job.
+type envelope struct {
+	ID             ID     `json:"id"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	Task           string `json:"task"`
+	Version        int    `json:"version,omitempty"`
+	Payload        []byte `json:"payload"`
+}
+
+// Registry maps task type names to constructors and codecs, so jobs can be persisted and restored.
+// Options (retry, timeout, deadline, panic handler) are not marshaled, they must be given again when unmarshaling.
+type Registry struct {
+	lock  sync.RWMutex
+	types map[string]*taskType
+	names map[reflect.Type]string
+}
+
+// Register adds a task type by a stable name. The constructor must return a new pointer to a task
+// which is a Task or a ContextTask. The payload is JSON, unless another codec is set.
+func (r *Registry) Register(name string, constructor func() interface{}, opts ...TaskOption) error {
+	if name == "" {
+		return errors.New("empty task name")
+	}
+	if constructor == nil {
+		return errors.New("nil task constructor")
+	}
+
+	task := constructor()
+	switch task.(type) {
+	case Task, ContextTask:
+	default:
+		return fmt.Errorf("task %q is neither Task nor ContextTask", name)
+	}
+
+	t := &taskType{name: name, constructor: constructor, codec: JSONCodec{}}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.types[name]; ok {
+		return fmt.Errorf("task %q already registered", name)
+	}
+
+	goType := reflect.TypeOf(task)
+	if other, ok := r.names[goType]; ok {
+		return fmt.Errorf("task type %s already registered as %q", goType, other)
+	}
+
+	r.types[name] = t
+	r.names[goType] = name
+
+	return nil
+}
+
+// Name returns the name a task type was registered with.
+func (r *Registry) Name(task interface{}) (string, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	name, ok := r.names[reflect.TypeOf(task)]
+	return name, ok
+}
+
+// Marshal returns the ID, task name, version and payload of a job.
+func (r *Registry) Marshal(j *Job) ([]byte, error) {
+	r.lock.RLock()
+	t, ok := r.types[r.names[reflect.TypeOf(j.task)]]
+	r.lock.RUnlock()
+
+	if !ok {
+		return nil, &UnknownTaskError{Name: fmt.Sprintf("%T", j.task)}
+	}
+
+	payload, err := t.codec.Encode(j.task)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode task %q: %w", t.name, err)
+	}
+
+	return json.Marshal(envelope{ID: j.id, IdempotencyKey: j.key, Task: t.name, Version: t.version, Payload: payload})
+}
+
+// Unmarshal creates a job from data returned by Marshal. The job has the same ID and idempotency key,
+// with given options.
+func (r *Registry) Unmarshal(data []byte, opts ...Option) (*Job, error) {
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+
+	r.lock.RLock()
+	t, ok := r.types[env.Task]
+	r.lock.RUnlock()
+
+	if !ok {
+		return nil, &UnknownTaskError{Name: env.Task}
+	}
+
+	payload, err := t.upgrade(env.Version, env.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	task := t.constructor()
+	if err := t.codec.Decode(payload, task); err != nil {
+		return nil, fmt.Errorf("cannot decode task %q: %w", t.name, err)
+	}
+
+	opts = append(opts[:len(opts):len(opts)], WithID(env.ID))
+	if env.IdempotencyKey != "" {
+		opts = append(opts, WithIdempotencyKey(env.IdempotencyKey))
+	}
+
+	if contextTask, ok := task.(ContextTask); ok {
+		return NewContext(contextTask, opts...)
+	}
+	return New(task.(Task), opts...) // nolint:errcheck
+}
+
+// upgrade migrates a payload of an older version to the current one.
+func (t *taskType) upgrade(version int, payload []byte) ([]byte, error) {
+	switch {
+	case version == t.version:
+		return payload, nil
+	case version > t.version:
+		return nil, fmt.Errorf("task %q version %d is newer than %d", t.name, version, t.version)
+	case t.migrate == nil:
+		return nil, fmt.Errorf("task %q has no migration from version %d", t.name, version)
+	}
+
+	payload, err := t.migrate(version, payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot migrate task %q from version %d: %w", t.name, version, err)
+	}
+
+	return payload, nil
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		types: make(map[string]*taskType),
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// Register adds a task type to the default registry.
+func Register(name string, constructor func() interface{}, opts ...TaskOption) error {
+	return defaultRegistry.Register(name, constructor, opts...)
+}
+
+// Marshal returns the ID, task name, version and payload of a job, using the default registry.
+func Marshal(j *Job) ([]byte, error) {
+	return defaultRegistry.Marshal(j)
+}
+
+// Unmarshal creates a job from data returned by Marshal, using the default registry.
+func Unmarshal(data []byte, opts ...Option) (*Job, error) {
+	return defaultRegistry.Unmarshal(data, opts...)
+}
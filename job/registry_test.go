@@ -0,0 +1,190 @@
+package job_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/stretchr/testify/assert"
+)
+
+type sumTask struct {
+	A, B int
+}
+
+func (t *sumTask) Run(*job.Job) (interface{}, error) {
+	return t.A + t.B, nil
+}
+
+type greetTask struct {
+	Name string `json:"name"`
+}
+
+func (t *greetTask) RunContext(context.Context) (interface{}, error) {
+	return "hello " + t.Name, nil
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := job.NewRegistry()
+	assert.NoError(t, registry.Register("sum", func() interface{} { return &sumTask{} }))
+
+	assert.Error(t, registry.Register("", func() interface{} { return &sumTask{} }))
+	assert.Error(t, registry.Register("nil", nil))
+	assert.Error(t, registry.Register("sum", func() interface{} { return &greetTask{} }))
+	assert.Error(t, registry.Register("other", func() interface{} { return &sumTask{} }))
+	assert.Error(t, registry.Register("string", func() interface{} { return new(string) }))
+
+	name, ok := registry.Name(&sumTask{})
+	assert.True(t, ok)
+	assert.Equal(t, "sum", name)
+
+	_, ok = registry.Name(&greetTask{})
+	assert.False(t, ok)
+}
+
+func TestRegistry_MarshalUnmarshal(t *testing.T) {
+	registry := job.NewRegistry()
+	assert.NoError(t, registry.Register("sum", func() interface{} { return &sumTask{} }, job.WithCodec(job.GobCodec{})))
+	assert.NoError(t, registry.Register("greet", func() interface{} { return &greetTask{} }))
+
+	original, err := job.New(&sumTask{A: 1, B: 2})
+	assert.NoError(t, err)
+
+	data, err := registry.Marshal(original)
+	assert.NoError(t, err)
+
+	restored, err := registry.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, original.ID(), restored.ID())
+	assert.Equal(t, &sumTask{A: 1, B: 2}, restored.Task())
+
+	future := restored.Run()
+	future.Wait()
+	assert.NoError(t, future.Error())
+	assert.Equal(t, 3, future.Result())
+
+	contextJob, err := job.NewContext(&greetTask{Name: "job"}, job.WithID("greeting"), job.WithIdempotencyKey("request"))
+	assert.NoError(t, err)
+
+	data, err = registry.Marshal(contextJob)
+	assert.NoError(t, err)
+
+	var envelope struct {
+		ID      string
+		Task    string
+		Payload []byte
+	}
+	assert.NoError(t, json.Unmarshal(data, &envelope))
+	assert.Equal(t, "greeting", envelope.ID)
+	assert.Equal(t, "greet", envelope.Task)
+	assert.JSONEq(t, `{"name":"job"}`, string(envelope.Payload))
+
+	restored, err = registry.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID("greeting"), restored.ID())
+	assert.Equal(t, "request", restored.IdempotencyKey())
+
+	future = restored.Run()
+	future.Wait()
+	assert.NoError(t, future.Error())
+	assert.Equal(t, "hello job", future.Result())
+
+	_, err = registry.Unmarshal([]byte("{"))
+	assert.Error(t, err)
+	_, err = registry.Unmarshal([]byte(`{"id":"id","task":"greet","payload":"ew=="}`))
+	assert.Error(t, err)
+}
+
+func TestRegistry_UnknownTask(t *testing.T) {
+	registry := job.NewRegistry()
+
+	unknown, err := job.New(&sumTask{})
+	assert.NoError(t, err)
+
+	_, err = registry.Marshal(unknown)
+	var unknownErr *job.UnknownTaskError
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, "*job_test.sumTask", unknownErr.Name)
+
+	_, err = registry.Unmarshal([]byte(`{"id":"id","task":"sum","payload":"e30="}`))
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, "sum", unknownErr.Name)
+	assert.Equal(t, `task "sum" is not registered`, err.Error())
+}
+
+func TestRegistry_Version(t *testing.T) {
+	v1 := job.NewRegistry()
+	assert.NoError(t, v1.Register("greet", func() interface{} { return &greetTask{} }))
+
+	original, err := job.NewContext(&greetTask{Name: "job"})
+	assert.NoError(t, err)
+	data, err := v1.Marshal(original)
+	assert.NoError(t, err)
+
+	var migrated []int
+	v2 := job.NewRegistry()
+	assert.NoError(t, v2.Register("greet", func() interface{} { return &greetTask{} },
+		job.WithVersion(2, func(version int, payload []byte) ([]byte, error) {
+			migrated = append(migrated, version)
+
+			var old struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(payload, &old); err != nil {
+				return nil, err
+			}
+			return json.Marshal(greetTask{Name: "migrated " + old.Name})
+		}),
+	))
+
+	restored, err := v2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, &greetTask{Name: "migrated job"}, restored.Task())
+	assert.Equal(t, []int{0}, migrated)
+
+	// The current version is not migrated
+	data, err = v2.Marshal(restored)
+	assert.NoError(t, err)
+
+	_, err = v2.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, migrated)
+
+	// A newer version cannot be read by an older registry
+	_, err = v1.Unmarshal(data)
+	assert.Error(t, err)
+
+	noMigration := job.NewRegistry()
+	assert.NoError(t, noMigration.Register("greet", func() interface{} { return &greetTask{} }, job.WithVersion(3, nil)))
+	_, err = noMigration.Unmarshal(data)
+	assert.Error(t, err)
+}
+
+func TestMarshal(t *testing.T) {
+	// The default registry lives as long as the process, tests can be run more than once
+	registerDefault.Do(func() {
+		assert.NoError(t, job.Register("default.sum", func() interface{} { return &defaultSumTask{} }))
+	})
+
+	original, err := job.New(&defaultSumTask{A: 2, B: 3})
+	assert.NoError(t, err)
+
+	data, err := job.Marshal(original)
+	assert.NoError(t, err)
+
+	restored, err := job.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, original.ID(), restored.ID())
+	assert.Equal(t, original.Task(), restored.Task())
+}
+
+var registerDefault sync.Once // nolint:gochecknoglobals
+
+type defaultSumTask sumTask
+
+func (t *defaultSumTask) Run(*job.Job) (interface{}, error) {
+	return t.A + t.B, nil
+}
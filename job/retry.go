@@ -0,0 +1,96 @@
+package job
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides if and when a failed task is run again.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times the task is run, including the first one.
+	// Zero or one means the task is not retried.
+	MaxAttempts int
+
+	// Backoff gives the duration to wait before the next attempt. Nil means no waiting.
+	Backoff Backoff
+
+	// MaxElapsed stops retrying if the next attempt would start after this duration
+	// since the first attempt. Zero means no limit.
+	MaxElapsed time.Duration
+
+	// Retryable tells if an error is transient, so the task can be run again.
+	// Nil means all errors are retryable.
+	Retryable func(error) bool
+}
+
+// Backoff returns the duration to wait before an attempt, given the number of the failed attempts
+// and the previous duration waited (zero before the second attempt).
+type Backoff func(failed int, previous time.Duration) time.Duration
+
+// ConstantBackoff waits the same duration before every attempt.
+func ConstantBackoff(d time.Duration) Backoff {
+	return func(int, time.Duration) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff doubles the duration before every attempt, starting with base, up to max.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(failed int, _ time.Duration) time.Duration {
+		d := base
+		for i := 1; i < failed; i++ {
+			d *= 2
+			if d >= max || d <= 0 {
+				return max
+			}
+		}
+
+		if d > max {
+			return max
+		}
+
+		return d
+	}
+}
+
+// DecorrelatedJitterBackoff waits a random duration between base and three times the previous
+// duration, up to max. It spreads the attempts of many jobs failing at the same time.
+func DecorrelatedJitterBackoff(base, max time.Duration) Backoff {
+	return func(_ int, previous time.Duration) time.Duration {
+		if previous < base {
+			previous = base
+		}
+
+		upper := previous * 3
+		if upper > max || upper <= 0 {
+			upper = max
+		}
+		if upper <= base {
+			return upper
+		}
+
+		return base + time.Duration(rand.Int63n(int64(upper-base))) // nolint:gosec
+	}
+}
+
+// next returns the duration to wait before the next attempt and false if no attempt must be made.
+func (p *RetryPolicy) next(failed int, err error, previous, elapsed time.Duration) (time.Duration, bool) {
+	if failed >= p.MaxAttempts {
+		return 0, false
+	}
+
+	if p.Retryable != nil && !p.Retryable(err) {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if p.Backoff != nil {
+		delay = p.Backoff(failed, previous)
+	}
+
+	if p.MaxElapsed > 0 && elapsed+delay > p.MaxElapsed {
+		return 0, false
+	}
+
+	return delay, true
+}
@@ -0,0 +1,156 @@
+package job_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstantBackoff(t *testing.T) {
+	backoff := job.ConstantBackoff(time.Second)
+	assert.Equal(t, time.Second, backoff(1, 0))
+	assert.Equal(t, time.Second, backoff(5, time.Second))
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := job.ExponentialBackoff(time.Second, time.Second*10)
+	assert.Equal(t, time.Second, backoff(1, 0))
+	assert.Equal(t, time.Second*2, backoff(2, 0))
+	assert.Equal(t, time.Second*8, backoff(4, 0))
+	assert.Equal(t, time.Second*10, backoff(5, 0))
+	assert.Equal(t, time.Second*10, backoff(100, 0))
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	base, max := time.Millisecond*10, time.Second
+	backoff := job.DecorrelatedJitterBackoff(base, max)
+
+	previous := time.Duration(0)
+	for i := 1; i < 100; i++ {
+		d := backoff(i, previous)
+		assert.GreaterOrEqual(t, d, base)
+		assert.LessOrEqual(t, d, max)
+		if previous > 0 {
+			assert.LessOrEqual(t, d, previous*3)
+		}
+		previous = d
+	}
+}
+
+func TestJob_Retry(t *testing.T) {
+	task := &flakyTask{failures: 2}
+	retryJob, err := job.New(task, job.WithRetry(job.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     job.ConstantBackoff(time.Millisecond),
+	}))
+	assert.NoError(t, err)
+
+	future := retryJob.Run()
+
+	assert.Equal(t, 3, future.Result())
+	assert.NoError(t, future.Error())
+	assert.Equal(t, 3, future.Attempts())
+	assert.Len(t, future.Errors(), 2)
+	assert.Nil(t, task.onError)
+}
+
+func TestJob_RetryExhausted(t *testing.T) {
+	task := &flakyTask{failures: 5}
+	retryJob, err := job.New(task, job.WithRetry(job.RetryPolicy{
+		MaxAttempts: 3,
+	}))
+	assert.NoError(t, err)
+
+	future := retryJob.Run()
+	future.Wait()
+
+	assert.Error(t, future.Error())
+	assert.Equal(t, 3, future.Attempts())
+	assert.Len(t, future.Errors(), 3)
+	assert.Equal(t, future.Error(), task.onError)
+	assert.Equal(t, 1, task.onErrorCalls)
+}
+
+func TestJob_RetryNotRetryable(t *testing.T) {
+	permanent := errors.New("permanent")
+	task := &flakyTask{failures: 5, err: permanent}
+	retryJob, err := job.New(task, job.WithRetry(job.RetryPolicy{
+		MaxAttempts: 3,
+		Retryable: func(err error) bool {
+			return !errors.Is(err, permanent)
+		},
+	}))
+	assert.NoError(t, err)
+
+	future := retryJob.Run()
+
+	assert.Equal(t, 1, future.Attempts())
+	assert.Equal(t, permanent, future.Error())
+}
+
+func TestJob_RetryMaxElapsed(t *testing.T) {
+	task := &flakyTask{failures: 5}
+	retryJob, err := job.New(task, job.WithRetry(job.RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     job.ConstantBackoff(time.Millisecond * 20),
+		MaxElapsed:  time.Millisecond * 30,
+	}))
+	assert.NoError(t, err)
+
+	future := retryJob.Run()
+
+	assert.Equal(t, 2, future.Attempts())
+	assert.Error(t, future.Error())
+}
+
+func TestJob_CancelDuringBackoff(t *testing.T) {
+	task := &flakyTask{failures: 5}
+	retryJob, err := job.New(task, job.WithRetry(job.RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     job.ConstantBackoff(time.Hour),
+	}))
+	assert.NoError(t, err)
+
+	future := retryJob.Run()
+
+	time.AfterFunc(time.Millisecond*10, func() {
+		retryJob.Cancel(errors.New("stop retrying"))
+	})
+
+	assert.Equal(t, 1, future.Attempts())
+	assert.True(t, future.IsCanceled())
+	assert.EqualError(t, task.onCancel, "stop retrying")
+	assert.Nil(t, task.onError)
+}
+
+type flakyTask struct {
+	failures     int
+	err          error
+	runs         int
+	onError      error
+	onErrorCalls int
+	onCancel     error
+}
+
+func (f *flakyTask) Run(*job.Job) (interface{}, error) {
+	f.runs++
+	if f.runs <= f.failures {
+		if f.err != nil {
+			return nil, f.err
+		}
+		return nil, errors.New("transient")
+	}
+	return f.runs, nil
+}
+
+func (f *flakyTask) OnError(err error) {
+	f.onError = err
+	f.onErrorCalls++
+}
+
+func (f *flakyTask) OnCancel(err error) {
+	f.onCancel = err
+}
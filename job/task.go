@@ -1,10 +1,19 @@
 package job
 
+import "context"
+
 // Task is the interface a task must implement to be part of a job.
+// The task can check if the job was canceled with IsCanceled.
 type Task interface {
 	Run(*Job) (interface{}, error)
 }
 
+// ContextTask is the interface a task must implement to be part of a job created with NewContext.
+// The context is canceled when the job is canceled, with the cancellation error as cause.
+type ContextTask interface {
+	RunContext(context.Context) (interface{}, error)
+}
+
 // SuccessfulTask is the interface a task must implement to be notified
 // after is executed successfully. OnSuccess receives the task result.
 type SuccessfulTask interface {
@@ -0,0 +1,78 @@
+package job_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob_WithTimeout(t *testing.T) {
+	task := &contextTask{block: true}
+	taskJob, err := job.NewContext(task, job.WithTimeout(time.Millisecond*10))
+	assert.NoError(t, err)
+
+	future := taskJob.Run()
+
+	assert.Nil(t, future.Result())
+	assert.Equal(t, job.ErrDeadlineExceeded, future.Error())
+	assert.True(t, errors.Is(future.Error(), context.DeadlineExceeded))
+	assert.True(t, future.IsCanceled())
+	assert.Equal(t, job.ErrDeadlineExceeded, task.onCancel)
+}
+
+func TestJob_WithDeadline(t *testing.T) {
+	task := &timeoutTask{}
+	taskJob, err := job.New(task, job.WithDeadline(time.Now().Add(time.Millisecond*10)))
+	assert.NoError(t, err)
+
+	future := taskJob.Run()
+	future.Wait()
+
+	// Not cancelable tasks are notified with an error
+	assert.Equal(t, job.ErrDeadlineExceeded, future.Error())
+	assert.Equal(t, job.ErrDeadlineExceeded, task.onError)
+}
+
+func TestJob_DeadlineFromContext(t *testing.T) {
+	task := &contextTask{block: true}
+	taskJob, err := job.NewContext(task, job.WithTimeout(time.Hour))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	future := taskJob.RunContext(ctx)
+
+	assert.Nil(t, future.Result())
+	assert.Equal(t, job.ErrDeadlineExceeded, future.Error())
+	assert.Equal(t, job.ErrDeadlineExceeded, task.onCancel)
+}
+
+func TestJob_FinishedBeforeTimeout(t *testing.T) {
+	taskJob, err := job.NewContext(&contextTask{}, job.WithTimeout(time.Hour))
+	assert.NoError(t, err)
+
+	future := taskJob.Run()
+
+	assert.Equal(t, "done", future.Result())
+	assert.NoError(t, future.Error())
+	assert.False(t, future.IsCanceled())
+	assert.NoError(t, taskJob.Cause())
+}
+
+type timeoutTask struct {
+	onError error
+}
+
+func (t *timeoutTask) Run(j *job.Job) (interface{}, error) {
+	<-j.Context().Done()
+	return nil, nil
+}
+
+func (t *timeoutTask) OnError(err error) {
+	t.onError = err
+}
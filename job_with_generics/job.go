@@ -8,6 +8,7 @@ import (
 	"errors"
 	"sync"
 
+	"github.com/andreiavrammsd/workexec/recovery"
 	"github.com/google/uuid"
 )
 
@@ -19,7 +20,10 @@ type Job[T any] struct {
 	id     uuid.UUID
 	task   Task[T]
 	cancel error
-	lock   sync.RWMutex
+	// next is the future of the next run, if it was asked for before the run
+	next    *Future[T]
+	options options
+	lock    sync.RWMutex
 }
 
 // ID returns the job unique identifier.
@@ -27,10 +31,27 @@ func (j *Job[T]) ID() ID {
 	return ID(j.id.String())
 }
 
+// Future returns the future of the next run of the job, so it can be handed out before the job is run.
+func (j *Job[T]) Future() *Future[T] {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.next == nil {
+		j.next = &Future[T]{done: make(chan struct{})}
+	}
+	return j.next
+}
+
 // Run starts executing the job task and returns a Future.
+// The future is the one returned by Future, if it was called since the previous run.
 func (j *Job[T]) Run() *Future[T] {
-	future := &Future[T]{
-		done: make(chan struct{}),
+	j.lock.Lock()
+	future := j.next
+	j.next = nil
+	j.lock.Unlock()
+
+	if future == nil {
+		future = &Future[T]{done: make(chan struct{})}
 	}
 
 	go func() {
@@ -42,6 +63,28 @@ func (j *Job[T]) Run() *Future[T] {
 	return future
 }
 
+// Discard settles the future of the next run with given error, as canceled, without running the task.
+// It is for a job which will never run, like a job dropped by a runner which was stopped.
+// A future which was already taken by Run is not affected.
+func (j *Job[T]) Discard(err error) {
+	j.lock.Lock()
+	future := j.next
+	j.next = nil
+	j.lock.Unlock()
+
+	if future == nil {
+		return
+	}
+
+	if err == nil {
+		err = errors.New("discarded")
+	}
+
+	future.err = err
+	future.canceled = true
+	close(future.done)
+}
+
 // Cancel asks the job to stop.
 func (j *Job[T]) Cancel(err error) {
 	if _, ok := j.task.(CancelableTask); !ok {
@@ -64,8 +107,20 @@ func (j *Job[T]) IsCanceled() bool {
 	return j.cancel != nil
 }
 
+// call runs the task and converts a panic into a PanicError.
+func (j *Job[T]) call() (result T, err error) {
+	defer func() {
+		if perr := recovery.Recovered(recover(), j.options.panicHandler); perr != nil {
+			var zero T
+			result, err = zero, perr
+		}
+	}()
+
+	return j.task.Run(j)
+}
+
 func (j *Job[T]) run() (result T, err error) {
-	result, err = j.task.Run(j)
+	result, err = j.call()
 
 	if task, ok := j.task.(CancelableTask); ok {
 		j.lock.RLock()
@@ -94,7 +149,7 @@ func (j *Job[T]) run() (result T, err error) {
 }
 
 // New creates a new job with a given task.
-func New[T any](task Task[T]) (*Job[T], error) {
+func New[T any](task Task[T], opts ...Option) (*Job[T], error) {
 	if task == nil {
 		return nil, errors.New("nil task passed to job")
 	}
@@ -103,6 +158,9 @@ func New[T any](task Task[T]) (*Job[T], error) {
 		task: task,
 		id:   uuid.New(),
 	}
+	for _, opt := range opts {
+		opt(&job.options)
+	}
 
 	return job, nil
 }
@@ -1,10 +1,12 @@
 package job_with_generics_test
 
 import (
+	"errors"
 	"log"
 	"testing"
 
 	"github.com/andreiavrammsd/workexec/job_with_generics"
+	"github.com/andreiavrammsd/workexec/recovery"
 )
 
 func TestNew(t *testing.T) {
@@ -48,3 +50,95 @@ type normalTask struct {
 func (n *normalTask) Run(*job_with_generics.Job[string]) (string, error) {
 	return "", nil
 }
+
+func TestJob_Panic(t *testing.T) {
+	task := &panicTask{}
+	var handled *job_with_generics.PanicError
+	taskJob, err := job_with_generics.New[int](task, job_with_generics.WithPanicHandler(func(err *recovery.PanicError) {
+		handled = err
+	}))
+	if err != nil {
+		t.Fatal("expected no error")
+	}
+
+	future := taskJob.Run()
+
+	if future.Result() != 0 {
+		t.Error("expected zero result")
+	}
+
+	var panicErr *recovery.PanicError
+	if !errors.As(future.Error(), &panicErr) {
+		t.Fatalf("expected panic error, got %v", future.Error())
+	}
+
+	if panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Error("expected panic value and stack")
+	}
+
+	if task.onError != future.Error() {
+		t.Error("expected OnError to be called with the panic error")
+	}
+
+	if handled != panicErr {
+		t.Error("expected panic handler to be called with the panic error")
+	}
+}
+
+type panicTask struct {
+	onError error
+}
+
+func (p *panicTask) Run(*job_with_generics.Job[int]) (int, error) {
+	panic("boom")
+}
+
+func (p *panicTask) OnError(err error) {
+	p.onError = err
+}
+
+func TestJob_Future(t *testing.T) {
+	taskJob, err := job_with_generics.New[string](&normalTask{})
+	if err != nil {
+		t.Fatal("expected no error")
+	}
+
+	future := taskJob.Future()
+	if taskJob.Future() != future {
+		t.Error("expected same future until the job is run")
+	}
+
+	if taskJob.Run() != future {
+		t.Error("expected run to settle the future")
+	}
+	future.Wait()
+
+	if taskJob.Run() == future {
+		t.Error("expected a new future for another run")
+	}
+}
+
+func TestJob_Discard(t *testing.T) {
+	taskJob, err := job_with_generics.New[string](&normalTask{})
+	if err != nil {
+		t.Fatal("expected no error")
+	}
+
+	// Nothing to discard without a future
+	taskJob.Discard(errors.New("stopped"))
+
+	future := taskJob.Future()
+	stopped := errors.New("stopped")
+	taskJob.Discard(stopped)
+	future.Wait()
+
+	if !errors.Is(future.Error(), stopped) {
+		t.Error("expected future to be settled with the error")
+	}
+	if !future.IsCanceled() {
+		t.Error("expected future to be canceled")
+	}
+	if taskJob.Future() == future {
+		t.Error("expected a new future for the next run")
+	}
+}
@@ -0,0 +1,171 @@
+// Package metrics exports measurements of runners and task executors in the Prometheus text format.
+// A Registry holds counters, gauges and histograms and renders them through an http.Handler.
+package metrics
+
+import (
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds used for latencies.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10} // nolint:gochecknoglobals
+
+// Labels are the constant label names and values of a metric.
+type Labels map[string]string
+
+// Metric is a measurement which can be registered.
+type Metric interface {
+	describe() *desc
+	write(w io.Writer) error
+}
+
+// desc identifies a metric.
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels Labels
+}
+
+func (d *desc) describe() *desc {
+	return d
+}
+
+// Counter is a value which only goes up.
+type Counter struct {
+	desc
+	// value is aligned for 64-bit atomic operations on 32-bit platforms too
+	value atomic.Uint64
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add adds given value to the counter.
+func (c *Counter) Add(v uint64) {
+	c.value.Add(v)
+}
+
+// Value returns the current value.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+func (c *Counter) write(w io.Writer) error {
+	return writeSample(w, c.name, c.labels, float64(c.Value()))
+}
+
+// NewCounter creates a counter.
+func NewCounter(name, help string, labels Labels) *Counter {
+	return &Counter{desc: desc{name: name, help: help, typ: "counter", labels: labels}}
+}
+
+// Func is a metric whose value is read when it is collected.
+type Func struct {
+	desc
+	fn func() float64
+}
+
+// Value returns the current value.
+func (f *Func) Value() float64 {
+	return f.fn()
+}
+
+func (f *Func) write(w io.Writer) error {
+	return writeSample(w, f.name, f.labels, f.fn())
+}
+
+// NewGaugeFunc creates a gauge whose value is given by fn.
+func NewGaugeFunc(name, help string, labels Labels, fn func() float64) *Func {
+	return &Func{desc: desc{name: name, help: help, typ: "gauge", labels: labels}, fn: fn}
+}
+
+// NewCounterFunc creates a counter whose value is given by fn, which must never decrease.
+func NewCounterFunc(name, help string, labels Labels, fn func() float64) *Func {
+	return &Func{desc: desc{name: name, help: help, typ: "counter", labels: labels}, fn: fn}
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	desc
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+	lock    sync.Mutex
+}
+
+// Observe adds a value to the histogram.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.lock.Lock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+	h.lock.Unlock()
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.count
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.lock.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	sum, count := h.sum, h.count
+	h.lock.Unlock()
+
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += counts[i]
+		if err := writeSample(w, h.name+"_bucket", withLabel(h.labels, "le", formatFloat(upper)),
+			float64(cumulative)); err != nil {
+			return err
+		}
+	}
+
+	if err := writeSample(w, h.name+"_bucket", withLabel(h.labels, "le", formatFloat(math.Inf(1))),
+		float64(count)); err != nil {
+		return err
+	}
+	if err := writeSample(w, h.name+"_sum", h.labels, sum); err != nil {
+		return err
+	}
+	return writeSample(w, h.name+"_count", h.labels, float64(count))
+}
+
+// NewHistogram creates a histogram with given bucket upper bounds. Nil buckets means DefaultBuckets.
+func NewHistogram(name, help string, labels Labels, buckets []float64) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Histogram{
+		desc:    desc{name: name, help: help, typ: "histogram", labels: labels},
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func withLabel(labels Labels, name, value string) Labels {
+	l := make(Labels, len(labels)+1)
+	for k, v := range labels {
+		l[k] = v
+	}
+	l[name] = value
+	return l
+}
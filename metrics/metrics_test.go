@@ -0,0 +1,140 @@
+package metrics_test
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andreiavrammsd/workexec/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_Handler(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	requests := metrics.NewCounter("http_requests_total", "Requests.\nServed.", metrics.Labels{"path": `/a"b\`})
+	other := metrics.NewCounter("http_requests_total", "Requests.\nServed.", metrics.Labels{"path": "/"})
+	temperature := metrics.NewGaugeFunc("temperature", "Temperature.", nil, func() float64 { return -1.5 })
+	latency := metrics.NewHistogram("latency_seconds", "Latency.", metrics.Labels{"op": "get"}, []float64{1, 0.1})
+
+	assert.NoError(t, registry.Register(requests, temperature, latency, other))
+
+	requests.Inc()
+	requests.Add(2)
+	latency.Observe(0.05)
+	latency.Observe(0.1)
+	latency.Observe(0.5)
+	latency.Observe(5)
+
+	exposition := scrape(t, registry)
+
+	assert.Equal(t, "counter", exposition.types["http_requests_total"])
+	assert.Equal(t, `Requests.\nServed.`, exposition.help["http_requests_total"])
+	assert.Equal(t, float64(3), exposition.samples[`http_requests_total{path="/a\"b\\"}`])
+	assert.Equal(t, float64(0), exposition.samples[`http_requests_total{path="/"}`])
+
+	assert.Equal(t, "gauge", exposition.types["temperature"])
+	assert.Equal(t, -1.5, exposition.samples["temperature"])
+
+	assert.Equal(t, "histogram", exposition.types["latency_seconds"])
+	assert.Equal(t, float64(2), exposition.samples[`latency_seconds_bucket{le="0.1",op="get"}`])
+	assert.Equal(t, float64(3), exposition.samples[`latency_seconds_bucket{le="1",op="get"}`])
+	assert.Equal(t, float64(4), exposition.samples[`latency_seconds_bucket{le="+Inf",op="get"}`])
+	assert.Equal(t, 5.65, math.Round(exposition.samples[`latency_seconds_sum{op="get"}`]*100)/100)
+	assert.Equal(t, float64(4), exposition.samples[`latency_seconds_count{op="get"}`])
+
+	registry.Unregister(temperature)
+	_, ok := scrape(t, registry).samples["temperature"]
+	assert.False(t, ok)
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := metrics.NewRegistry()
+	counter := metrics.NewCounter("count", "Count.", nil)
+	assert.NoError(t, registry.Register(counter))
+
+	assert.Error(t, registry.Register(metrics.NewCounter("count", "Count.", nil)), "duplicate")
+	assert.Error(t, registry.Register(metrics.NewCounter("count", "Other.", metrics.Labels{"a": "b"})),
+		"different help")
+	assert.Error(t, registry.Register(metrics.NewGaugeFunc("count", "Count.", metrics.Labels{"a": "b"}, nil)),
+		"different type")
+	assert.Error(t, registry.Register(metrics.NewCounter("1count", "", nil)), "invalid name")
+	assert.Error(t, registry.Register(metrics.NewCounter("c", "", metrics.Labels{"__a": ""})), "reserved label")
+
+	// Nothing is registered if one metric is not valid
+	valid := metrics.NewCounter("valid", "", nil)
+	assert.Error(t, registry.Register(valid, metrics.NewCounter("in-valid", "", nil)))
+	assert.NoError(t, registry.Register(valid))
+}
+
+type exposition struct {
+	help    map[string]string
+	types   map[string]string
+	samples map[string]float64
+}
+
+var sampleLine = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)((?:\{.*\})?) (\S+)$`) // nolint:gochecknoglobals
+
+// scrape gets the metrics through the handler and parses the text exposition format.
+// Samples are keyed by their name and labels as rendered.
+func scrape(t *testing.T, registry *metrics.Registry) exposition {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	e := exposition{
+		help:    make(map[string]string),
+		types:   make(map[string]string),
+		samples: make(map[string]float64),
+	}
+
+	scanner := bufio.NewScanner(recorder.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "# HELP ") {
+			parts := strings.SplitN(strings.TrimPrefix(line, "# HELP "), " ", 2)
+			e.help[parts[0]] = parts[1]
+			continue
+		}
+
+		if strings.HasPrefix(line, "# TYPE ") {
+			parts := strings.SplitN(strings.TrimPrefix(line, "# TYPE "), " ", 2)
+			_, described := e.help[parts[0]]
+			assert.True(t, described, "TYPE after HELP: %s", line)
+			e.types[parts[0]] = parts[1]
+			continue
+		}
+
+		match := sampleLine.FindStringSubmatch(line)
+		if !assert.NotNil(t, match, "sample line: %q", line) {
+			continue
+		}
+
+		name := match[1]
+		family := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "_bucket"), "_sum"), "_count")
+		_, typed := e.types[name]
+		_, familyTyped := e.types[family]
+		assert.True(t, typed || familyTyped, "sample without type: %s", line)
+
+		value, err := strconv.ParseFloat(match[3], 64)
+		assert.NoError(t, err)
+
+		key := name + match[2]
+		_, duplicate := e.samples[key]
+		assert.False(t, duplicate, "duplicate sample: %s", line)
+		e.samples[key] = value
+	}
+	assert.NoError(t, scanner.Err())
+
+	return e
+}
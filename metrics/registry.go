@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry holds metrics and renders them in the Prometheus text exposition format.
+type Registry struct {
+	metrics []Metric
+	lock    sync.RWMutex
+}
+
+// Register adds metrics to the registry. Metrics with the same name must have the same type and help
+// and different labels.
+func (r *Registry) Register(metrics ...Metric) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// Nothing is registered if a metric is not valid
+	all := append([]Metric(nil), r.metrics...)
+
+	for _, m := range metrics {
+		d := m.describe()
+		if !validName(d.name) {
+			return fmt.Errorf("invalid metric name %q", d.name)
+		}
+		for name := range d.labels {
+			if !validName(name) || strings.HasPrefix(name, "__") {
+				return fmt.Errorf("invalid label name %q of metric %s", name, d.name)
+			}
+		}
+
+		for _, registered := range all {
+			rd := registered.describe()
+			if rd.name != d.name {
+				continue
+			}
+			if rd.typ != d.typ || rd.help != d.help {
+				return fmt.Errorf("metric %s registered with different type or help", d.name)
+			}
+			if formatLabels(rd.labels) == formatLabels(d.labels) {
+				return fmt.Errorf("metric %s%s already registered", d.name, formatLabels(d.labels))
+			}
+		}
+
+		all = append(all, m)
+	}
+
+	r.metrics = all
+
+	return nil
+}
+
+// Unregister removes metrics from the registry.
+func (r *Registry) Unregister(metrics ...Metric) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, m := range metrics {
+		for i, registered := range r.metrics {
+			if registered == m {
+				r.metrics = append(r.metrics[:i], r.metrics[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// WriteTo renders all metrics. Metrics with the same name are grouped, in the order of registration.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.RLock()
+	metrics := append([]Metric(nil), r.metrics...)
+	r.lock.RUnlock()
+
+	var names []string
+	families := make(map[string][]Metric)
+	for _, m := range metrics {
+		name := m.describe().name
+		if _, ok := families[name]; !ok {
+			names = append(names, name)
+		}
+		families[name] = append(families[name], m)
+	}
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, name := range names {
+		d := families[name][0].describe()
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.typ) // nolint:errcheck
+
+		for _, m := range families[name] {
+			if err := m.write(cw); err != nil {
+				return cw.n, err
+			}
+		}
+	}
+
+	return cw.n, cw.w.Flush()
+}
+
+// Handler returns an http.Handler which renders all metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		r.WriteTo(w) // nolint:errcheck
+	})
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// countingWriter counts the bytes written for WriteTo.
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func writeSample(w io.Writer, name string, labels Labels, value float64) error {
+	_, err := fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(labels), formatFloat(value))
+	return err
+}
+
+// formatLabels renders labels sorted by name, or an empty string if there are no labels.
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(labels[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"github.com/andreiavrammsd/workexec/runner"
+)
+
+// RunnerMetrics measures a runner. Counters are read from runner.Runner.Totals, so they are exact.
+// Latencies are observed from the runner events, which follow the drop policy of runner.Runner.Subscribe,
+// so they can miss jobs under load; the dropped events are exported too.
+type RunnerMetrics struct {
+	Enqueued  *Func
+	Succeeded *Func
+	Failed    *Func
+	Canceled  *Func
+	WaitTime  *Histogram
+	RunTime   *Histogram
+
+	registry *Registry
+	metrics  []Metric
+	remove   func()
+}
+
+// Close stops measuring the runner and removes its metrics from the registry.
+func (m *RunnerMetrics) Close() {
+	m.remove()
+	m.registry.Unregister(m.metrics...)
+}
+
+func (m *RunnerMetrics) observe(event runner.Event) {
+	switch event.Type {
+	case runner.EventStarted:
+		m.WaitTime.Observe(event.Duration.Seconds())
+	case runner.EventSucceeded, runner.EventFailed:
+		m.RunTime.Observe(event.Duration.Seconds())
+	case runner.EventCanceled:
+		// Delayed jobs canceled before running have no run time
+		if event.Duration > 0 {
+			m.RunTime.Observe(event.Duration.Seconds())
+		}
+	default:
+	}
+}
+
+// RegisterRunner adds metrics of a runner to the registry. They are labeled with the name of the runner.
+func RegisterRunner(registry *Registry, r *runner.Runner, name string) (*RunnerMetrics, error) {
+	labels := Labels{"runner": name}
+	status := func(value func(runner.Status) int) func() float64 {
+		return func() float64 {
+			return float64(value(r.Status()))
+		}
+	}
+
+	total := func(value func(runner.Totals) uint64) func() float64 {
+		return func() float64 {
+			return float64(value(r.Totals()))
+		}
+	}
+
+	m := &RunnerMetrics{
+		Enqueued: NewCounterFunc("workexec_runner_jobs_enqueued_total", "Jobs given to the runner.", labels,
+			total(func(t runner.Totals) uint64 { return t.Enqueued })),
+		Succeeded: NewCounterFunc("workexec_runner_jobs_succeeded_total", "Jobs finished without error.", labels,
+			total(func(t runner.Totals) uint64 { return t.Succeeded })),
+		Failed: NewCounterFunc("workexec_runner_jobs_failed_total", "Jobs finished with error.", labels,
+			total(func(t runner.Totals) uint64 { return t.Failed })),
+		Canceled: NewCounterFunc("workexec_runner_jobs_canceled_total", "Jobs canceled.", labels,
+			total(func(t runner.Totals) uint64 { return t.Canceled })),
+		WaitTime: NewHistogram("workexec_runner_job_wait_seconds",
+			"Time jobs waited in the queue before running.", labels, nil),
+		RunTime: NewHistogram("workexec_runner_job_run_seconds", "Time jobs ran.", labels, nil),
+
+		registry: registry,
+	}
+
+	m.metrics = []Metric{
+		NewGaugeFunc("workexec_runner_concurrency", "Number of worker routines.", labels,
+			status(func(s runner.Status) int { return s.Concurrency })),
+		NewGaugeFunc("workexec_runner_jobs_running", "Jobs run by workers.", labels,
+			status(func(s runner.Status) int { return s.RunningJobs })),
+		NewGaugeFunc("workexec_runner_jobs_queued", "Jobs waiting in the queue.", labels,
+			status(func(s runner.Status) int {
+				var pending int
+				for _, count := range s.PendingJobs {
+					pending += count
+				}
+				return pending
+			})),
+		NewGaugeFunc("workexec_runner_jobs_delayed", "Jobs waiting for their time to be enqueued.", labels,
+			status(func(s runner.Status) int { return s.DelayedJobs })),
+		NewGaugeFunc("workexec_runner_jobs_throttled", "Jobs waiting for the rate limiter.", labels,
+			status(func(s runner.Status) int { return s.ThrottledJobs })),
+		NewGaugeFunc("workexec_runner_jobs_leaked", "Jobs which exceeded their deadline and are still running.",
+			labels, status(func(s runner.Status) int { return s.LeakedJobs })),
+		NewCounterFunc("workexec_runner_events_dropped_total", "Runner events missed by subscribers.", labels,
+			func() float64 { return float64(r.Status().DroppedEvents) }),
+		m.Enqueued, m.Succeeded, m.Failed, m.Canceled, m.WaitTime, m.RunTime,
+	}
+
+	if err := registry.Register(m.metrics...); err != nil {
+		return nil, err
+	}
+
+	m.remove = r.Hook(m.observe)
+
+	return m, nil
+}
@@ -0,0 +1,74 @@
+package metrics_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/metrics"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterRunner(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 2})
+	r.Start()
+	defer r.Stop()
+
+	registry := metrics.NewRegistry()
+	runnerMetrics, err := metrics.RegisterRunner(registry, r, "main")
+	assert.NoError(t, err)
+
+	_, err = metrics.RegisterRunner(registry, r, "main")
+	assert.Error(t, err, "registered twice")
+
+	succeeded, err := job.New(&task{})
+	assert.NoError(t, err)
+	failed, err := job.New(&task{err: errors.New("failed")})
+	assert.NoError(t, err)
+	delayed, err := job.New(&task{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Enqueue(succeeded, failed))
+	assert.NoError(t, r.EnqueueAfter(time.Hour, delayed))
+
+	// Latencies are observed from the events, after the counters
+	for runnerMetrics.Succeeded.Value() != 1 || runnerMetrics.Failed.Value() != 1 ||
+		runnerMetrics.RunTime.Count() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	exposition := scrape(t, registry)
+
+	assert.Equal(t, float64(2), exposition.samples[`workexec_runner_concurrency{runner="main"}`])
+	assert.Equal(t, float64(0), exposition.samples[`workexec_runner_jobs_running{runner="main"}`])
+	assert.Equal(t, float64(0), exposition.samples[`workexec_runner_jobs_queued{runner="main"}`])
+	assert.Equal(t, float64(1), exposition.samples[`workexec_runner_jobs_delayed{runner="main"}`])
+	assert.Equal(t, float64(0), exposition.samples[`workexec_runner_jobs_leaked{runner="main"}`])
+	assert.Equal(t, float64(0), exposition.samples[`workexec_runner_events_dropped_total{runner="main"}`])
+	assert.Equal(t, float64(3), exposition.samples[`workexec_runner_jobs_enqueued_total{runner="main"}`])
+	assert.Equal(t, float64(1), exposition.samples[`workexec_runner_jobs_succeeded_total{runner="main"}`])
+	assert.Equal(t, float64(1), exposition.samples[`workexec_runner_jobs_failed_total{runner="main"}`])
+	assert.Equal(t, float64(0), exposition.samples[`workexec_runner_jobs_canceled_total{runner="main"}`])
+	assert.Equal(t, float64(2), exposition.samples[`workexec_runner_job_wait_seconds_count{runner="main"}`])
+	assert.Equal(t, float64(2), exposition.samples[`workexec_runner_job_run_seconds_count{runner="main"}`])
+	assert.Equal(t, "histogram", exposition.types["workexec_runner_job_run_seconds"])
+
+	r.Cancel(delayed.ID())
+	for runnerMetrics.Canceled.Value() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, float64(2), scrape(t, registry).samples[`workexec_runner_job_run_seconds_count{runner="main"}`])
+
+	runnerMetrics.Close()
+	assert.Empty(t, scrape(t, registry).samples)
+}
+
+type task struct {
+	err error
+}
+
+func (t *task) Run(*job.Job) (interface{}, error) {
+	return nil, t.err
+}
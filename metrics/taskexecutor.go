@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/andreiavrammsd/workexec/taskexecutor"
+)
+
+// TaskExecutorMetrics measures a task executor. The gauges are read from the status of the executor.
+// The counters and latencies are updated only for the futures submitted through the Submit method
+// of TaskExecutorMetrics: futures submitted directly to the executor are not counted.
+type TaskExecutorMetrics struct {
+	Submitted *Counter
+	Succeeded *Counter
+	Failed    *Counter
+	Canceled  *Counter
+	WaitTime  *Histogram
+	RunTime   *Histogram
+
+	executor *taskexecutor.TaskExecutor
+	registry *Registry
+	metrics  []Metric
+}
+
+// Submit puts a future into the executor queue and measures it.
+func (m *TaskExecutorMetrics) Submit(future taskexecutor.Future) error {
+	f := &measuredFuture{Future: future, metrics: m, submitted: time.Now()}
+	if err := m.executor.Submit(f); err != nil {
+		return err
+	}
+
+	m.Submitted.Inc()
+
+	return nil
+}
+
+// Close removes the metrics of the executor from the registry.
+func (m *TaskExecutorMetrics) Close() {
+	m.registry.Unregister(m.metrics...)
+}
+
+// measuredFuture observes the time a future waited in the queue and the time it ran.
+// The executor calls Run, then Wait.
+type measuredFuture struct {
+	taskexecutor.Future
+	metrics   *TaskExecutorMetrics
+	submitted time.Time
+	started   time.Time
+}
+
+func (f *measuredFuture) Run() {
+	f.started = time.Now()
+	f.metrics.WaitTime.Observe(f.started.Sub(f.submitted).Seconds())
+
+	f.Future.Run()
+}
+
+func (f *measuredFuture) Wait() {
+	f.Future.Wait()
+
+	f.metrics.RunTime.Observe(time.Since(f.started).Seconds())
+
+	_, err := f.Future.Result()
+	switch {
+	case f.Future.IsCanceled():
+		f.metrics.Canceled.Inc()
+	case err != nil:
+		f.metrics.Failed.Inc()
+	default:
+		f.metrics.Succeeded.Inc()
+	}
+}
+
+// RegisterTaskExecutor adds metrics of a task executor to the registry. They are labeled with the name
+// of the executor. Futures must be submitted through the returned TaskExecutorMetrics to be counted
+// and timed, the executor does not report futures submitted to it directly.
+func RegisterTaskExecutor(
+	registry *Registry, te *taskexecutor.TaskExecutor, name string,
+) (*TaskExecutorMetrics, error) {
+	labels := Labels{"executor": name}
+	status := func(value func(taskexecutor.Status) float64) func() float64 {
+		return func() float64 {
+			return value(te.Status())
+		}
+	}
+
+	m := &TaskExecutorMetrics{
+		Submitted: NewCounter("workexec_taskexecutor_tasks_submitted_total", "Tasks submitted.", labels),
+		Succeeded: NewCounter("workexec_taskexecutor_tasks_succeeded_total", "Tasks finished without error.", labels),
+		Failed:    NewCounter("workexec_taskexecutor_tasks_failed_total", "Tasks finished with error.", labels),
+		Canceled:  NewCounter("workexec_taskexecutor_tasks_canceled_total", "Tasks canceled.", labels),
+		WaitTime: NewHistogram("workexec_taskexecutor_task_wait_seconds",
+			"Time tasks waited in the queue before running.", labels, nil),
+		RunTime: NewHistogram("workexec_taskexecutor_task_run_seconds", "Time tasks ran.", labels, nil),
+
+		executor: te,
+		registry: registry,
+	}
+
+	m.metrics = []Metric{
+		NewGaugeFunc("workexec_taskexecutor_concurrency", "Number of worker routines.", labels,
+			status(func(s taskexecutor.Status) float64 { return float64(s.Concurrency) })),
+		NewGaugeFunc("workexec_taskexecutor_tasks_running", "Tasks run by workers.", labels,
+			status(func(s taskexecutor.Status) float64 { return float64(s.RunningTasks) })),
+		NewGaugeFunc("workexec_taskexecutor_tasks_queued", "Tasks waiting in the queue.", labels,
+			status(func(s taskexecutor.Status) float64 { return float64(s.QueuedTasks) })),
+		NewGaugeFunc("workexec_taskexecutor_tasks_throttled", "Tasks waiting for the rate limiter.", labels,
+			status(func(s taskexecutor.Status) float64 { return float64(s.ThrottledTasks) })),
+		m.Submitted, m.Succeeded, m.Failed, m.Canceled, m.WaitTime, m.RunTime,
+	}
+
+	if err := registry.Register(m.metrics...); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
@@ -0,0 +1,65 @@
+package metrics_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/future"
+	"github.com/andreiavrammsd/workexec/metrics"
+	"github.com/andreiavrammsd/workexec/taskexecutor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterTaskExecutor(t *testing.T) {
+	te := taskexecutor.New(taskexecutor.Config{Concurrency: 1})
+	te.Start()
+
+	registry := metrics.NewRegistry()
+	teMetrics, err := metrics.RegisterTaskExecutor(registry, te, "main")
+	assert.NoError(t, err)
+
+	succeeded, err := future.New(&futureTask{})
+	assert.NoError(t, err)
+	failed, err := future.New(&futureTask{err: errors.New("failed")})
+	assert.NoError(t, err)
+	canceled, err := future.New(&futureTask{})
+	assert.NoError(t, err)
+	canceled.Cancel()
+
+	assert.NoError(t, teMetrics.Submit(succeeded))
+	assert.NoError(t, teMetrics.Submit(failed))
+	assert.NoError(t, teMetrics.Submit(canceled))
+
+	for teMetrics.Succeeded.Value()+teMetrics.Failed.Value()+teMetrics.Canceled.Value() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	exposition := scrape(t, registry)
+
+	assert.Equal(t, float64(1), exposition.samples[`workexec_taskexecutor_concurrency{executor="main"}`])
+	assert.Equal(t, float64(0), exposition.samples[`workexec_taskexecutor_tasks_queued{executor="main"}`])
+	assert.Equal(t, float64(0), exposition.samples[`workexec_taskexecutor_tasks_throttled{executor="main"}`])
+	assert.Equal(t, float64(3), exposition.samples[`workexec_taskexecutor_tasks_submitted_total{executor="main"}`])
+	assert.Equal(t, float64(1), exposition.samples[`workexec_taskexecutor_tasks_succeeded_total{executor="main"}`])
+	assert.Equal(t, float64(1), exposition.samples[`workexec_taskexecutor_tasks_failed_total{executor="main"}`])
+	assert.Equal(t, float64(1), exposition.samples[`workexec_taskexecutor_tasks_canceled_total{executor="main"}`])
+	assert.Equal(t, float64(3), exposition.samples[`workexec_taskexecutor_task_wait_seconds_count{executor="main"}`])
+	assert.Equal(t, float64(3), exposition.samples[`workexec_taskexecutor_task_run_seconds_count{executor="main"}`])
+
+	te.Stop()
+	te.Wait()
+	assert.Error(t, teMetrics.Submit(succeeded))
+	assert.Equal(t, uint64(3), teMetrics.Submitted.Value())
+
+	teMetrics.Close()
+	assert.Empty(t, scrape(t, registry).samples)
+}
+
+type futureTask struct {
+	err error
+}
+
+func (t *futureTask) Run(func() bool) (interface{}, error) {
+	return nil, t.err
+}
@@ -0,0 +1,9 @@
+package promise
+
+import "github.com/andreiavrammsd/workexec/recovery"
+
+// PanicError is the error of an executor which panicked.
+type PanicError = recovery.PanicError
+
+// PanicHandler is notified when an executor panics, before the error is delivered.
+type PanicHandler = recovery.PanicHandler
@@ -9,6 +9,8 @@ package promise
 
 import (
 	"sync"
+
+	"github.com/andreiavrammsd/workexec/recovery"
 )
 
 // Executor interface must be implemented to be called inside a Promise.
@@ -29,6 +31,7 @@ type Promise struct {
 	err       error
 	then      Then
 	error     Error
+	onPanic   PanicHandler
 	lock      sync.RWMutex
 }
 
@@ -48,6 +51,15 @@ func (p *Promise) Error(f Error) *Promise {
 	return p
 }
 
+// OnPanic sets the function notified when an executor panics. The panic is delivered as a PanicError
+// to the fail callback too.
+func (p *Promise) OnPanic(f PanicHandler) *Promise {
+	p.lock.Lock()
+	p.onPanic = f
+	p.lock.Unlock()
+	return p
+}
+
 // Async executes executors asynchronous.
 func (p *Promise) Async() {
 	go func() {
@@ -84,13 +96,14 @@ func (p *Promise) exec() {
 			// Stop executors on first error
 			p.lock.RLock()
 			stop := p.err != nil
+			onPanic := p.onPanic
 			p.lock.RUnlock()
 			if stop {
 				return
 			}
 
 			// Call executor
-			if err := executor.Execute(); err != nil {
+			if err := execute(executor, onPanic); err != nil {
 				p.lock.Lock()
 				p.err = err
 				p.lock.Unlock()
@@ -106,3 +119,14 @@ func (p *Promise) exec() {
 
 	wg.Wait()
 }
+
+// execute calls the executor and converts a panic into a PanicError.
+func execute(executor Executor, handler PanicHandler) (err error) {
+	defer func() {
+		if perr := recovery.Recovered(recover(), handler); perr != nil {
+			err = perr
+		}
+	}()
+
+	return executor.Execute()
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/andreiavrammsd/workexec/promise"
+	"github.com/andreiavrammsd/workexec/recovery"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -101,3 +102,26 @@ func (d *division) Execute() error {
 	d.result = d.a / d.b
 	return nil
 }
+
+func TestPromise_Panic(t *testing.T) {
+	var onError error
+	var handled *promise.PanicError
+	err := promise.New(panicExecutor{}).Error(func(err error) {
+		onError = err
+	}).OnPanic(func(err *promise.PanicError) {
+		handled = err
+	}).Await()
+
+	var panicErr *recovery.PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.Equal(t, err, onError)
+	assert.Same(t, panicErr, handled)
+}
+
+type panicExecutor struct{}
+
+func (panicExecutor) Execute() error {
+	panic("boom")
+}
@@ -0,0 +1,124 @@
+package promise_with_generics
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNoPromises is the error of Race called without promises.
+var ErrNoPromises = errors.New("no promises passed")
+
+// AggregateError is the error of Any when all promises failed. Errors are in the order of the promises.
+type AggregateError struct {
+	Errors []error
+}
+
+func (e *AggregateError) Error() string {
+	if len(e.Errors) == 0 {
+		return "all promises failed: " + ErrNoPromises.Error()
+	}
+
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "all promises failed: " + strings.Join(messages, "; ")
+}
+
+func (e *AggregateError) Unwrap() []error {
+	return e.Errors
+}
+
+// Result is the outcome of a settled promise.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// All returns a promise with the values of the promises, in order. It fails with the first error.
+func All[T any](promises ...*Promise[T]) *Promise[[]T] {
+	return combine(promises, func(results <-chan indexed[T]) ([]T, error) {
+		values := make([]T, len(promises))
+		for range promises {
+			r := <-results
+			if r.Err != nil {
+				return nil, r.Err
+			}
+			values[r.index] = r.Value
+		}
+		return values, nil
+	})
+}
+
+// AllSettled returns a promise with the outcomes of all promises, in order. It does not fail.
+func AllSettled[T any](promises ...*Promise[T]) *Promise[[]Result[T]] {
+	return combine(promises, func(results <-chan indexed[T]) ([]Result[T], error) {
+		settled := make([]Result[T], len(promises))
+		for range promises {
+			r := <-results
+			settled[r.index] = r.Result
+		}
+		return settled, nil
+	})
+}
+
+// Any returns a promise with the value of the first promise which is fulfilled.
+// It fails with an AggregateError if all promises fail.
+func Any[T any](promises ...*Promise[T]) *Promise[T] {
+	return combine(promises, func(results <-chan indexed[T]) (T, error) {
+		errs := make([]error, len(promises))
+		for range promises {
+			r := <-results
+			if r.Err == nil {
+				return r.Value, nil
+			}
+			errs[r.index] = r.Err
+		}
+
+		var zero T
+		return zero, &AggregateError{Errors: errs}
+	})
+}
+
+// Race returns a promise settled as the first promise which is settled.
+// It fails with ErrNoPromises if no promises are passed.
+func Race[T any](promises ...*Promise[T]) *Promise[T] {
+	return combine(promises, func(results <-chan indexed[T]) (T, error) {
+		if len(promises) == 0 {
+			var zero T
+			return zero, ErrNoPromises
+		}
+
+		r := <-results
+		return r.Value, r.Err
+	})
+}
+
+// indexed is the outcome of the promise at index.
+type indexed[T any] struct {
+	Result[T]
+	index int
+}
+
+// combine returns a promise which starts the promises and applies given function
+// to their outcomes, in order of settlement.
+func combine[T, U any](promises []*Promise[T], fn func(<-chan indexed[T]) (U, error)) *Promise[U] {
+	return New[U](ExecutorFunc[U](func() (U, error) {
+		for _, p := range promises {
+			if p == nil {
+				var zero U
+				return zero, errors.New("nil promise passed")
+			}
+		}
+
+		results := make(chan indexed[T], len(promises))
+		for i, p := range promises {
+			go func(i int, p *Promise[T]) {
+				value, err := p.Await()
+				results <- indexed[T]{Result: Result[T]{Value: value, Err: err}, index: i}
+			}(i, p)
+		}
+
+		return fn(results)
+	}))
+}
@@ -0,0 +1,53 @@
+package promise_with_generics_test
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/andreiavrammsd/workexec/promise_with_generics"
+)
+
+func ExampleThen() {
+	words := promise_with_generics.New[string](promise_with_generics.ExecutorFunc[string](func() (string, error) {
+		return "promise with generics", nil
+	}))
+
+	count := promise_with_generics.Then(words, func(text string) (int, error) {
+		return len(strings.Fields(text)), nil
+	}).Finally(func() {
+		fmt.Println("Done")
+	})
+
+	value, err := count.Await()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(value)
+
+	// Output:
+	// Done
+	// 3
+}
+
+// Promises without a value, run concurrently. All starts every promise, fails with the first rejection
+// while the others keep running, and fails if a promise is nil. Group behaves like promise.New(executors...).
+func ExampleAll() {
+	printer := func(text string) *promise_with_generics.Promise[struct{}] {
+		return promise_with_generics.New[struct{}](promise_with_generics.ExecutorFunc[struct{}](func() (struct{}, error) {
+			fmt.Println(text)
+			return struct{}{}, nil
+		}))
+	}
+
+	_, err := promise_with_generics.All(printer("1"), printer("2"), printer("3")).Await()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Unordered output:
+	// 1
+	// 2
+	// 3
+}
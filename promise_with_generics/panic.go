@@ -0,0 +1,31 @@
+package promise_with_generics
+
+import "github.com/andreiavrammsd/workexec/recovery"
+
+// PanicError is the error of an executor which panicked.
+type PanicError = recovery.PanicError
+
+// PanicHandler is notified when an executor panics, before the error is delivered.
+type PanicHandler = recovery.PanicHandler
+
+// Option allows setup of a promise.
+type Option func(*options)
+
+type options struct {
+	panicHandler PanicHandler
+}
+
+// WithPanicHandler sets a function to be notified when the executor panics.
+// Promises returned by Then, Catch and Finally notify the same function.
+func WithPanicHandler(handler PanicHandler) Option {
+	return func(o *options) {
+		o.panicHandler = handler
+	}
+}
+
+// inherit sets the options of a promise to a promise created from it.
+func inherit(o options) Option {
+	return func(to *options) {
+		*to = o
+	}
+}
@@ -0,0 +1,188 @@
+// Package promise_with_generics implements an asynchronous operation of an executor which returns a value.
+// A Promise settles exactly once, with a value or an error, and keeps its outcome:
+// awaiting it again does not call the executor again.
+// Then, Catch and Finally return new promises, so work can be chained.
+// All, AllSettled, Any and Race combine promises. Group calls executors like promise.New.
+package promise_with_generics
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/andreiavrammsd/workexec/recovery"
+)
+
+// Executor interface must be implemented to be called inside a Promise.
+type Executor[T any] interface {
+	// Execute is the called method when a Promise starts. It returns the value of the promise.
+	Execute() (T, error)
+}
+
+// ExecutorFunc is a function used as an Executor.
+type ExecutorFunc[T any] func() (T, error)
+
+// Execute calls the function.
+func (f ExecutorFunc[T]) Execute() (T, error) {
+	return f()
+}
+
+// Promise represents an async Executor execution.
+type Promise[T any] struct {
+	executor Executor[T]
+	once     sync.Once
+	done     chan struct{}
+	value    T
+	err      error
+	options  options
+}
+
+// Async starts the executor without blocking, if it was not started.
+func (p *Promise[T]) Async() {
+	p.once.Do(func() {
+		go p.settle()
+	})
+}
+
+// Await starts the executor if it was not started and blocks until the promise is settled.
+// It returns the value and error of the executor.
+func (p *Promise[T]) Await() (T, error) {
+	p.Async()
+	<-p.done
+	return p.value, p.err
+}
+
+// Done returns a channel which is closed when the promise is settled.
+func (p *Promise[T]) Done() <-chan struct{} {
+	return p.done
+}
+
+// Catch returns a promise which turns the error of the promise into the value of given function.
+// The value of a fulfilled promise is passed on.
+func (p *Promise[T]) Catch(fn func(error) (T, error)) *Promise[T] {
+	return New[T](ExecutorFunc[T](func() (T, error) {
+		value, err := p.Await()
+		if err == nil {
+			return value, nil
+		}
+		return fn(err)
+	}), inherit(p.options))
+}
+
+// Finally returns a promise which calls given function when the promise is settled
+// and settles with the same value and error.
+func (p *Promise[T]) Finally(fn func()) *Promise[T] {
+	return New[T](ExecutorFunc[T](func() (T, error) {
+		value, err := p.Await()
+		fn()
+		return value, err
+	}), inherit(p.options))
+}
+
+func (p *Promise[T]) settle() {
+	defer close(p.done)
+
+	if p.executor == nil {
+		p.err = errors.New("nil executor passed")
+		return
+	}
+
+	p.value, p.err = execute(p.executor, p.options.panicHandler)
+}
+
+// Then returns a promise with the value of given function applied to the value of the promise.
+// The function is not called if the promise failed, the error is passed on.
+func Then[T, U any](p *Promise[T], fn func(T) (U, error)) *Promise[U] {
+	return New[U](ExecutorFunc[U](func() (U, error) {
+		value, err := p.Await()
+		if err != nil {
+			var zero U
+			return zero, err
+		}
+		return fn(value)
+	}), inherit(p.options))
+}
+
+// New creates a Promise with given executor. The executor is called by Async or Await.
+func New[T any](executor Executor[T], opts ...Option) *Promise[T] {
+	p := &Promise[T]{
+		executor: executor,
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&p.options)
+	}
+	return p
+}
+
+// Resolve creates a settled Promise with given value.
+func Resolve[T any](value T) *Promise[T] {
+	return settled(value, nil)
+}
+
+// Reject creates a settled Promise with given error.
+func Reject[T any](err error) *Promise[T] {
+	var zero T
+	return settled(zero, err)
+}
+
+// Group creates a Promise which calls given executors concurrently, like promise.New(executors...).
+// Nil executors are skipped, and the executors which did not start yet are not called after the first error.
+// The promise fails with the first error.
+func Group(executors ...Executor[struct{}]) *Promise[struct{}] {
+	return New[struct{}](ExecutorFunc[struct{}](func() (struct{}, error) {
+		var lock sync.Mutex
+		var first error
+		wg := sync.WaitGroup{}
+
+		for _, executor := range executors {
+			if executor == nil {
+				continue
+			}
+
+			wg.Add(1)
+
+			go func(executor Executor[struct{}]) {
+				defer wg.Done()
+
+				// Stop executors on first error
+				lock.Lock()
+				stop := first != nil
+				lock.Unlock()
+				if stop {
+					return
+				}
+
+				if _, err := execute(executor, nil); err != nil {
+					lock.Lock()
+					if first == nil {
+						first = err
+					}
+					lock.Unlock()
+				}
+			}(executor)
+		}
+
+		wg.Wait()
+
+		return struct{}{}, first
+	}))
+}
+
+func settled[T any](value T, err error) *Promise[T] {
+	p := &Promise[T]{value: value, err: err, done: make(chan struct{})}
+	p.once.Do(func() {})
+	close(p.done)
+	return p
+}
+
+// execute calls the executor and converts a panic into a PanicError.
+func execute[T any](executor Executor[T], handler PanicHandler) (value T, err error) {
+	defer func() {
+		if perr := recovery.Recovered(recover(), handler); perr != nil {
+			var zero T
+			value, err = zero, perr
+		}
+	}()
+
+	return executor.Execute()
+}
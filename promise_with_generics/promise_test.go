@@ -0,0 +1,226 @@
+package promise_with_generics_test
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/promise_with_generics"
+	"github.com/andreiavrammsd/workexec/recovery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromise_Await(t *testing.T) {
+	exec := &division{a: 4, b: 2}
+	p := promise_with_generics.New[int](exec)
+
+	value, err := p.Await()
+	assert.Equal(t, 2, value)
+	assert.NoError(t, err)
+
+	value, err = p.Await()
+	assert.Equal(t, 2, value)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), exec.calls.Load())
+
+	_, err = promise_with_generics.New[int](nil).Await()
+	assert.Error(t, err)
+}
+
+func TestPromise_Async(t *testing.T) {
+	exec := &division{a: 4, b: 2}
+	p := promise_with_generics.New[int](exec)
+
+	p.Async()
+	p.Async()
+	<-p.Done()
+
+	value, err := p.Await()
+	assert.Equal(t, 2, value)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), exec.calls.Load())
+}
+
+func TestThen(t *testing.T) {
+	p := promise_with_generics.New[int](&division{a: 8, b: 2})
+	half := promise_with_generics.Then(p, func(value int) (int, error) {
+		return value / 2, nil
+	})
+	text := promise_with_generics.Then(half, func(value int) (string, error) {
+		return strconv.Itoa(value), nil
+	})
+
+	value, err := text.Await()
+	assert.Equal(t, "2", value)
+	assert.NoError(t, err)
+
+	called := false
+	failed := promise_with_generics.Then(promise_with_generics.New[int](&division{a: 8}), func(value int) (int, error) {
+		called = true
+		return value, nil
+	})
+	_, err = failed.Await()
+	assert.EqualError(t, err, "division by zero")
+	assert.False(t, called)
+}
+
+func TestPromise_Catch(t *testing.T) {
+	value, err := promise_with_generics.New[int](&division{a: 8}).Catch(func(error) (int, error) {
+		return -1, nil
+	}).Await()
+	assert.Equal(t, -1, value)
+	assert.NoError(t, err)
+
+	value, err = promise_with_generics.Resolve(1).Catch(func(error) (int, error) {
+		return -1, nil
+	}).Await()
+	assert.Equal(t, 1, value)
+	assert.NoError(t, err)
+}
+
+func TestPromise_Finally(t *testing.T) {
+	called := false
+	value, err := promise_with_generics.Reject[int](errors.New("rejected")).Finally(func() {
+		called = true
+	}).Await()
+	assert.Equal(t, 0, value)
+	assert.EqualError(t, err, "rejected")
+	assert.True(t, called)
+}
+
+func TestPromise_Panic(t *testing.T) {
+	var handled *promise_with_generics.PanicError
+	source := promise_with_generics.New[int](
+		promise_with_generics.ExecutorFunc[int](func() (int, error) { return 1, nil }),
+		promise_with_generics.WithPanicHandler(func(err *promise_with_generics.PanicError) {
+			handled = err
+		}),
+	)
+
+	// The promise returned by Then notifies the handler of the promise it was created from
+	p := promise_with_generics.Then(source, func(int) (int, error) {
+		panic("boom")
+	})
+
+	_, err := p.Await()
+	var panicErr *recovery.PanicError
+	assert.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.Same(t, panicErr, handled)
+}
+
+func TestAll(t *testing.T) {
+	values, err := promise_with_generics.All(
+		delayed(1, nil, time.Millisecond*10),
+		promise_with_generics.Resolve(2),
+		delayed(3, nil, 0),
+	).Await()
+	assert.Equal(t, []int{1, 2, 3}, values)
+	assert.NoError(t, err)
+
+	values, err = promise_with_generics.All(
+		delayed(1, nil, time.Second*10),
+		promise_with_generics.Reject[int](errors.New("rejected")),
+	).Await()
+	assert.Nil(t, values)
+	assert.EqualError(t, err, "rejected")
+
+	_, err = promise_with_generics.All[int](nil).Await()
+	assert.Error(t, err)
+}
+
+func TestAllSettled(t *testing.T) {
+	results, err := promise_with_generics.AllSettled(
+		promise_with_generics.Resolve(1),
+		promise_with_generics.Reject[int](errors.New("rejected")),
+	).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, []promise_with_generics.Result[int]{
+		{Value: 1},
+		{Err: errors.New("rejected")},
+	}, results)
+}
+
+func TestAny(t *testing.T) {
+	value, err := promise_with_generics.Any(
+		promise_with_generics.Reject[int](errors.New("rejected")),
+		delayed(1, nil, time.Second*10),
+		delayed(2, nil, 0),
+	).Await()
+	assert.Equal(t, 2, value)
+	assert.NoError(t, err)
+
+	first := errors.New("first")
+	second := errors.New("second")
+	_, err = promise_with_generics.Any(delayed(0, first, time.Millisecond*10), delayed(0, second, 0)).Await()
+
+	var aggregateErr *promise_with_generics.AggregateError
+	assert.True(t, errors.As(err, &aggregateErr))
+	assert.Equal(t, []error{first, second}, aggregateErr.Errors)
+	assert.ErrorIs(t, err, first)
+	assert.EqualError(t, err, "all promises failed: first; second")
+}
+
+func TestRace(t *testing.T) {
+	_, err := promise_with_generics.Race(
+		delayed(1, nil, time.Second*10),
+		delayed(0, errors.New("rejected"), 0),
+	).Await()
+	assert.EqualError(t, err, "rejected")
+
+	_, err = promise_with_generics.Race[int]().Await()
+	assert.ErrorIs(t, err, promise_with_generics.ErrNoPromises)
+}
+
+func TestGroup(t *testing.T) {
+	var calls atomic.Int32
+	count := promise_with_generics.ExecutorFunc[struct{}](func() (struct{}, error) {
+		calls.Add(1)
+		return struct{}{}, nil
+	})
+
+	_, err := promise_with_generics.Group(count, nil, count).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// The executors which did not start before the error are not called
+	calls.Store(0)
+	executors := []promise_with_generics.Executor[struct{}]{
+		promise_with_generics.ExecutorFunc[struct{}](func() (struct{}, error) {
+			return struct{}{}, errors.New("failed")
+		}),
+	}
+	for i := 0; i < 1000; i++ {
+		executors = append(executors, count)
+	}
+
+	_, err = promise_with_generics.Group(executors...).Await()
+	assert.EqualError(t, err, "failed")
+	assert.Less(t, calls.Load(), int32(1000))
+
+	_, err = promise_with_generics.Group().Await()
+	assert.NoError(t, err)
+}
+
+type division struct {
+	a     int
+	b     int
+	calls atomic.Int32
+}
+
+func (d *division) Execute() (int, error) {
+	d.calls.Add(1)
+	if d.b == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return d.a / d.b, nil
+}
+
+func delayed(value int, err error, delay time.Duration) *promise_with_generics.Promise[int] {
+	return promise_with_generics.New[int](promise_with_generics.ExecutorFunc[int](func() (int, error) {
+		time.Sleep(delay)
+		return value, err
+	}))
+}
@@ -0,0 +1,265 @@
+// Package ratelimit limits how often work starts, with token buckets shared by all work and by key.
+// A bucket holds up to a burst of tokens and is refilled at a rate of tokens per second.
+// Starting work takes a token from the global bucket and from the bucket of its key.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+)
+
+// maxIdleKeys is the number of key buckets after which the full ones are removed.
+const maxIdleKeys = 1024
+
+// Limit is a rate with a burst.
+type Limit struct {
+	// Rate is the number of tokens added per second. Zero means no limit.
+	Rate float64
+
+	// Burst is the maximum number of tokens, so the number of starts allowed at once after being idle.
+	// Defaults to one.
+	Burst int
+}
+
+func (l Limit) unlimited() bool {
+	return l.Rate <= 0
+}
+
+func (l Limit) burst() float64 {
+	if l.Burst < 1 {
+		return 1
+	}
+	return float64(l.Burst)
+}
+
+// Config allows setup of a limiter.
+type Config struct {
+	// Global is the limit of all work.
+	Global Limit
+
+	// PerKey is the limit of the work of each key, unless a key has its own limit set by SetKeyLimit.
+	// Work with an empty key is limited only globally.
+	PerKey Limit
+
+	// Clock is used to refill the buckets and to wait. Defaults to the system time.
+	Clock clock.Clock
+}
+
+// Limiter is a rate limiter. Limits can be changed while it is used.
+type Limiter struct {
+	clock  clock.Clock
+	global *bucket
+	perKey Limit
+	keys   map[string]*bucket
+	lock   sync.Mutex
+}
+
+// Allow takes a token for given key if one is available now, without waiting.
+func (l *Limiter) Allow(key string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.reserve(key) == 0
+}
+
+// Wait blocks until a token for given key is available and takes it.
+// It returns the cause of the context if it is done first.
+func (l *Limiter) Wait(ctx context.Context, key string) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return context.Cause(ctx)
+		}
+
+		l.lock.Lock()
+		delay := l.reserve(key)
+		l.lock.Unlock()
+
+		if delay == 0 {
+			return nil
+		}
+
+		// The limits could change while waiting, so the tokens are checked again
+		timer := l.clock.NewTimer(delay)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return context.Cause(ctx)
+		}
+	}
+}
+
+// SetLimit changes the global limit.
+func (l *Limiter) SetLimit(limit Limit) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.global.set(limit, l.clock.Now())
+}
+
+// SetPerKeyLimit changes the limit of the keys which do not have their own limit.
+func (l *Limiter) SetPerKeyLimit(limit Limit) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.perKey = limit
+
+	now := l.clock.Now()
+	for _, b := range l.keys {
+		if !b.own {
+			b.set(limit, now)
+		}
+	}
+}
+
+// SetKeyLimit sets the limit of a key, instead of the per key limit.
+func (l *Limiter) SetKeyLimit(key string, limit Limit) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+	b := l.bucket(key, now)
+	b.own = true
+	b.set(limit, now)
+}
+
+// Limits returns the global limit and the limit of given key.
+func (l *Limiter) Limits(key string) (global, perKey Limit) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if b, ok := l.keys[key]; ok {
+		return l.global.limit, b.limit
+	}
+	return l.global.limit, l.perKey
+}
+
+// reserve takes a token from the global bucket and from the bucket of the key if both have one.
+// Else it returns how long to wait for them. It must be called with the lock held.
+func (l *Limiter) reserve(key string) time.Duration {
+	now := l.clock.Now()
+
+	buckets := []*bucket{l.global}
+	if key != "" {
+		buckets = append(buckets, l.bucket(key, now))
+	}
+
+	var delay time.Duration
+	for _, b := range buckets {
+		b.refill(now)
+		if d := b.delay(); d > delay {
+			delay = d
+		}
+	}
+
+	if delay > 0 {
+		return delay
+	}
+
+	for _, b := range buckets {
+		b.take()
+	}
+	return 0
+}
+
+// bucket returns the bucket of a key, creating it if needed. It must be called with the lock held.
+func (l *Limiter) bucket(key string, now time.Time) *bucket {
+	if b, ok := l.keys[key]; ok {
+		return b
+	}
+
+	if len(l.keys) >= maxIdleKeys {
+		l.prune(now)
+	}
+
+	b := newBucket(l.perKey, now)
+	l.keys[key] = b
+	return b
+}
+
+// prune removes the buckets which are full and do not have their own limit, as they are the same as new ones.
+// It must be called with the lock held.
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.keys {
+		if b.own {
+			continue
+		}
+		b.refill(now)
+		if b.full() {
+			delete(l.keys, key)
+		}
+	}
+}
+
+// New creates a limiter. A limiter without limits allows all work.
+func New(c Config) *Limiter {
+	if c.Clock == nil {
+		c.Clock = clock.New()
+	}
+
+	return &Limiter{
+		clock:  c.Clock,
+		global: newBucket(c.Global, c.Clock.Now()),
+		perKey: c.PerKey,
+		keys:   make(map[string]*bucket),
+	}
+}
+
+// bucket is a token bucket.
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+	// own is true for a key with its own limit
+	own bool
+}
+
+// refill adds the tokens for the time passed since the last refill.
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 && !b.limit.unlimited() {
+		b.tokens = math.Min(b.limit.burst(), b.tokens+elapsed.Seconds()*b.limit.Rate)
+	}
+	b.last = now
+}
+
+// delay returns how long until a token is available.
+func (b *bucket) delay() time.Duration {
+	if b.limit.unlimited() || b.tokens >= 1 {
+		return 0
+	}
+
+	delay := time.Duration(math.Ceil((1 - b.tokens) / b.limit.Rate * float64(time.Second)))
+	if delay <= 0 {
+		delay = 1
+	}
+	return delay
+}
+
+func (b *bucket) take() {
+	if !b.limit.unlimited() {
+		b.tokens--
+	}
+}
+
+func (b *bucket) full() bool {
+	return b.limit.unlimited() || b.tokens >= b.limit.burst()
+}
+
+// set changes the limit, keeping the tokens gathered under the previous limit up to the new burst.
+func (b *bucket) set(limit Limit, now time.Time) {
+	b.refill(now)
+
+	if b.limit.unlimited() {
+		b.tokens = limit.burst()
+	}
+	b.limit = limit
+	b.tokens = math.Min(b.tokens, limit.burst())
+}
+
+func newBucket(limit Limit, now time.Time) *bucket {
+	return &bucket{limit: limit, tokens: limit.burst(), last: now}
+}
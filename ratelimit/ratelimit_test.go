@@ -0,0 +1,159 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/andreiavrammsd/workexec/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	l := ratelimit.New(ratelimit.Config{Global: ratelimit.Limit{Rate: 2, Burst: 3}, Clock: c})
+
+	assert.True(t, l.Allow(""))
+	assert.True(t, l.Allow(""))
+	assert.True(t, l.Allow(""))
+	assert.False(t, l.Allow(""))
+
+	c.Advance(time.Millisecond * 500)
+	assert.True(t, l.Allow(""))
+	assert.False(t, l.Allow(""))
+
+	c.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Allow(""))
+	}
+	assert.False(t, l.Allow(""))
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	l := ratelimit.New(ratelimit.Config{})
+	for i := 0; i < 100; i++ {
+		assert.True(t, l.Allow("key"))
+	}
+}
+
+func TestLimiter_PerKey(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	l := ratelimit.New(ratelimit.Config{PerKey: ratelimit.Limit{Rate: 1}, Clock: c})
+
+	assert.True(t, l.Allow("a"))
+	assert.False(t, l.Allow("a"))
+	assert.True(t, l.Allow("b"))
+	assert.True(t, l.Allow(""))
+	assert.True(t, l.Allow(""))
+
+	l.SetKeyLimit("a", ratelimit.Limit{Rate: 1, Burst: 2})
+	c.Advance(time.Second * 2)
+	assert.True(t, l.Allow("a"))
+	assert.True(t, l.Allow("a"))
+	assert.False(t, l.Allow("a"))
+
+	global, perKey := l.Limits("a")
+	assert.Equal(t, ratelimit.Limit{}, global)
+	assert.Equal(t, ratelimit.Limit{Rate: 1, Burst: 2}, perKey)
+
+	l.SetPerKeyLimit(ratelimit.Limit{})
+	assert.True(t, l.Allow("b"))
+	assert.True(t, l.Allow("b"))
+	assert.False(t, l.Allow("a"))
+}
+
+func TestLimiter_Global(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	l := ratelimit.New(ratelimit.Config{
+		Global: ratelimit.Limit{Rate: 1},
+		PerKey: ratelimit.Limit{Rate: 1},
+		Clock:  c,
+	})
+
+	assert.True(t, l.Allow("a"))
+	assert.False(t, l.Allow("b"))
+
+	c.Advance(time.Second)
+	assert.True(t, l.Allow("b"))
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	l := ratelimit.New(ratelimit.Config{Global: ratelimit.Limit{Rate: 1}, Clock: c})
+
+	assert.NoError(t, l.Wait(context.Background(), ""))
+
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(context.Background(), "")
+	}()
+
+	waitForTimers(t, c, 1)
+	c.Advance(time.Millisecond * 500)
+	select {
+	case <-done:
+		t.Fatal("expected to wait")
+	default:
+	}
+
+	c.Advance(time.Millisecond * 500)
+	assert.NoError(t, <-done)
+}
+
+func TestLimiter_WaitCanceled(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	l := ratelimit.New(ratelimit.Config{Global: ratelimit.Limit{Rate: 1}, Clock: c})
+	assert.True(t, l.Allow(""))
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(ctx, "")
+	}()
+
+	waitForTimers(t, c, 1)
+	cause := errors.New("not needed")
+	cancel(cause)
+	assert.Equal(t, cause, <-done)
+	assert.Equal(t, 0, c.Timers())
+
+	assert.Equal(t, cause, l.Wait(ctx, ""))
+}
+
+func TestLimiter_SetLimit(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	l := ratelimit.New(ratelimit.Config{Global: ratelimit.Limit{Rate: 1}, Clock: c})
+	assert.True(t, l.Allow(""))
+
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(context.Background(), "")
+	}()
+	waitForTimers(t, c, 1)
+
+	l.SetLimit(ratelimit.Limit{Rate: 10, Burst: 5})
+	c.Advance(time.Second)
+	assert.NoError(t, <-done)
+
+	for i := 0; i < 4; i++ {
+		assert.True(t, l.Allow(""))
+	}
+	assert.False(t, l.Allow(""))
+
+	l.SetLimit(ratelimit.Limit{})
+	assert.True(t, l.Allow(""))
+}
+
+func waitForTimers(t *testing.T, c *clock.Fake, count int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 5)
+	for c.Timers() != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d timers, got %d", count, c.Timers())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,43 @@
+// Package recovery holds the error of a recovered panic, shared by all the executors of the library.
+package recovery
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of a task which panicked.
+// The packages of the library alias it, so a panic is matched with one errors.As whichever package recovered it.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the routine which panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error) // nolint:errcheck
+	return err
+}
+
+// PanicHandler is notified when a task panics, before the error is delivered.
+type PanicHandler func(*PanicError)
+
+// Recovered converts a recovered value into a PanicError and notifies the handler if it is not nil.
+// It returns nil if there was no panic.
+func Recovered(v interface{}, handler PanicHandler) *PanicError {
+	if v == nil {
+		return nil
+	}
+
+	err := &PanicError{Value: v, Stack: debug.Stack()}
+	if handler != nil {
+		handler(err)
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+package recovery_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/andreiavrammsd/workexec/recovery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecovered(t *testing.T) {
+	assert.Nil(t, recovery.Recovered(nil, func(*recovery.PanicError) {
+		t.Error("handler called without panic")
+	}))
+
+	var handled *recovery.PanicError
+	cause := errors.New("boom")
+	err := recovery.Recovered(cause, func(err *recovery.PanicError) {
+		handled = err
+	})
+
+	assert.Same(t, err, handled)
+	assert.Equal(t, cause, err.Value)
+	assert.NotEmpty(t, err.Stack)
+	assert.EqualError(t, err, "task panicked: boom")
+	assert.ErrorIs(t, err, cause)
+
+	assert.NotNil(t, recovery.Recovered("boom", nil))
+}
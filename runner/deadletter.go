@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+// DeadLetter is a job which failed or was canceled. Err is the cancellation cause if the job was canceled
+// and the task returned no error.
+type DeadLetter struct {
+	Job        *job.Job
+	Priority   Priority
+	Key        string
+	Err        error
+	Canceled   bool
+	Attempts   int
+	EnqueuedAt time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// DeadLetterQueue stores jobs which failed, so they can be inspected and enqueued again.
+type DeadLetterQueue interface {
+	Put(DeadLetter) error
+	Get(job.ID) (DeadLetter, bool)
+	List() []DeadLetter
+	Remove(job.ID) (DeadLetter, bool)
+	Purge()
+}
+
+// MemoryDeadLetterQueue is a DeadLetterQueue kept in memory.
+type MemoryDeadLetterQueue struct {
+	capacity int
+	letters  []DeadLetter
+	lock     sync.RWMutex
+}
+
+// Put stores a dead letter. If the queue is full, the oldest dead letter is dropped.
+func (q *MemoryDeadLetterQueue) Put(letter DeadLetter) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.capacity > 0 && len(q.letters) == q.capacity {
+		q.letters = q.letters[1:]
+	}
+	q.letters = append(q.letters, letter)
+
+	return nil
+}
+
+// Get returns a dead letter by job id.
+func (q *MemoryDeadLetterQueue) Get(id job.ID) (DeadLetter, bool) {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	for _, letter := range q.letters {
+		if letter.Job.ID() == id {
+			return letter, true
+		}
+	}
+
+	return DeadLetter{}, false
+}
+
+// List returns all dead letters, oldest first.
+func (q *MemoryDeadLetterQueue) List() []DeadLetter {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	return append([]DeadLetter(nil), q.letters...)
+}
+
+// Remove deletes a dead letter by job id and returns it.
+func (q *MemoryDeadLetterQueue) Remove(id job.ID) (DeadLetter, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for i, letter := range q.letters {
+		if letter.Job.ID() == id {
+			q.letters = append(q.letters[:i], q.letters[i+1:]...)
+			return letter, true
+		}
+	}
+
+	return DeadLetter{}, false
+}
+
+// Purge deletes all dead letters.
+func (q *MemoryDeadLetterQueue) Purge() {
+	q.lock.Lock()
+	q.letters = nil
+	q.lock.Unlock()
+}
+
+// NewMemoryDeadLetterQueue creates a dead letter queue which holds at most capacity
+// dead letters. Zero capacity means no limit.
+func NewMemoryDeadLetterQueue(capacity int) *MemoryDeadLetterQueue {
+	return &MemoryDeadLetterQueue{
+		capacity: capacity,
+	}
+}
+
+// DeadLetters returns the jobs in the dead letter queue.
+func (r *Runner) DeadLetters() []DeadLetter {
+	if r.dlq == nil {
+		return nil
+	}
+	return r.dlq.List()
+}
+
+// DeadLetter returns a job in the dead letter queue by its id.
+func (r *Runner) DeadLetter(id job.ID) (DeadLetter, bool) {
+	if r.dlq == nil {
+		return DeadLetter{}, false
+	}
+	return r.dlq.Get(id)
+}
+
+// Requeue removes a job from the dead letter queue and enqueues it again with its priority and key.
+func (r *Runner) Requeue(id job.ID) error {
+	if r.dlq == nil {
+		return errors.New("no dead letter queue")
+	}
+
+	letter, ok := r.dlq.Remove(id)
+	if !ok {
+		return errors.New("job not found in dead letter queue")
+	}
+
+	opts := EnqueueOptions{Priority: letter.Priority, Key: letter.Key}
+	if err := r.EnqueueWithOptions(opts, letter.Job.Clone()); err != nil {
+		// Keep the job for a later attempt
+		r.dlq.Put(letter) // nolint:errcheck
+		return err
+	}
+
+	return nil
+}
+
+// PurgeDeadLetters deletes all jobs from the dead letter queue.
+func (r *Runner) PurgeDeadLetters() {
+	if r.dlq != nil {
+		r.dlq.Purge()
+	}
+}
+
+// deadLetter puts a job which is done into the dead letter queue if it failed.
+func (r *Runner) deadLetter(it *item, state State, err error, attempts int, started, finished time.Time) {
+	if r.dlq == nil || state == StateSucceeded {
+		return
+	}
+
+	canceled := state == StateCanceled
+	if canceled && !r.dlqCanceled {
+		return
+	}
+
+	letter := DeadLetter{
+		Job:        it.job,
+		Priority:   it.priority,
+		Key:        it.key,
+		Err:        err,
+		Canceled:   canceled,
+		Attempts:   attempts,
+		EnqueuedAt: it.enqueued,
+		StartedAt:  started,
+		FinishedAt: finished,
+	}
+
+	// There is no one to report the error to, the job is lost as it would be without a dead letter queue.
+	r.dlq.Put(letter) // nolint:errcheck
+}
@@ -0,0 +1,157 @@
+package runner_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryDeadLetterQueue(t *testing.T) {
+	q := runner.NewMemoryDeadLetterQueue(2)
+
+	jobs := make([]*job.Job, 3)
+	for i := range jobs {
+		j, err := job.New(&task{})
+		assert.NoError(t, err)
+		jobs[i] = j
+
+		assert.NoError(t, q.Put(runner.DeadLetter{Job: j}))
+	}
+
+	// Oldest was dropped
+	letters := q.List()
+	assert.Len(t, letters, 2)
+	assert.Equal(t, jobs[1].ID(), letters[0].Job.ID())
+	assert.Equal(t, jobs[2].ID(), letters[1].Job.ID())
+
+	_, ok := q.Get(jobs[0].ID())
+	assert.False(t, ok)
+
+	letter, ok := q.Get(jobs[1].ID())
+	assert.True(t, ok)
+	assert.Equal(t, jobs[1].ID(), letter.Job.ID())
+
+	letter, ok = q.Remove(jobs[1].ID())
+	assert.True(t, ok)
+	assert.Equal(t, jobs[1].ID(), letter.Job.ID())
+
+	_, ok = q.Remove(jobs[1].ID())
+	assert.False(t, ok)
+
+	q.Purge()
+	assert.Empty(t, q.List())
+}
+
+func TestRunner_DeadLetters(t *testing.T) {
+	r := runner.New(runner.Config{
+		Concurrency: 1,
+		DeadLetters: runner.NewMemoryDeadLetterQueue(0),
+	})
+	r.Start()
+	defer r.Stop()
+
+	flaky := &failingTask{failures: 1}
+	failed, err := job.New(flaky, job.WithRetry(job.RetryPolicy{MaxAttempts: 1}))
+	assert.NoError(t, err)
+
+	succeeded, err := job.New(&task{})
+	assert.NoError(t, err)
+
+	canceled, err := job.New(&cancelableTask{myTask: &myTask{}})
+	assert.NoError(t, err)
+	canceled.Cancel(nil)
+
+	assert.NoError(t, r.Enqueue(failed, succeeded, canceled))
+
+	for status := r.Status(); len(status.PendingJobs) > 0 || status.RunningJobs > 0; status = r.Status() {
+		time.Sleep(time.Millisecond)
+	}
+
+	letters := r.DeadLetters()
+	assert.Len(t, letters, 1, "canceled jobs are not dead lettered by default")
+
+	letter, ok := r.DeadLetter(failed.ID())
+	assert.True(t, ok)
+	assert.EqualError(t, letter.Err, "failed")
+	assert.False(t, letter.Canceled)
+	assert.Equal(t, 1, letter.Attempts)
+	assert.Equal(t, runner.PriorityNormal, letter.Priority)
+	assert.False(t, letter.EnqueuedAt.IsZero())
+	assert.False(t, letter.StartedAt.Before(letter.EnqueuedAt))
+	assert.False(t, letter.FinishedAt.Before(letter.StartedAt))
+
+	// The task succeeds the second time
+	assert.NoError(t, r.Requeue(failed.ID()))
+	assert.Error(t, r.Requeue(failed.ID()))
+
+	for flaky.runs() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(time.Millisecond * 10)
+	assert.Empty(t, r.DeadLetters())
+}
+
+func TestRunner_DeadLettersCanceled(t *testing.T) {
+	r := runner.New(runner.Config{
+		Concurrency:        1,
+		DeadLetters:        runner.NewMemoryDeadLetterQueue(0),
+		DeadLetterCanceled: true,
+	})
+	r.Start()
+	defer r.Stop()
+
+	canceled, err := job.New(&cancelableTask{myTask: &myTask{}})
+	assert.NoError(t, err)
+	canceled.Cancel(nil)
+
+	assert.NoError(t, r.Enqueue(canceled))
+
+	for len(r.DeadLetters()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	letter, ok := r.DeadLetter(canceled.ID())
+	assert.True(t, ok)
+	assert.True(t, letter.Canceled)
+
+	r.PurgeDeadLetters()
+	assert.Empty(t, r.DeadLetters())
+}
+
+func TestRunner_WithoutDeadLetters(t *testing.T) {
+	r := runner.New(runner.Config{})
+
+	assert.Nil(t, r.DeadLetters())
+	_, ok := r.DeadLetter(job.ID("id"))
+	assert.False(t, ok)
+	assert.Error(t, r.Requeue(job.ID("id")))
+	r.PurgeDeadLetters()
+}
+
+type failingTask struct {
+	failures int
+	count    int
+	lock     sync.Mutex
+}
+
+func (f *failingTask) Run(*job.Job) (interface{}, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.count++
+	if f.count <= f.failures {
+		return nil, errors.New("failed")
+	}
+	return nil, nil
+}
+
+func (f *failingTask) runs() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.count
+}
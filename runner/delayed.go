@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+// timer is a job waiting for its time to be enqueued.
+type timer struct {
+	item *item
+	at   time.Time
+	seq  uint64
+}
+
+// delayed holds jobs which must not start before a given time, earliest first.
+type delayed struct {
+	timers timers
+	seq    uint64
+}
+
+func (d *delayed) push(it *item, at time.Time) {
+	d.seq++
+	heap.Push(&d.timers, &timer{
+		item: it,
+		at:   at,
+		seq:  d.seq,
+	})
+}
+
+// popDue removes and returns the jobs which are due at given time.
+func (d *delayed) popDue(now time.Time) []*item {
+	var due []*item
+	for len(d.timers) > 0 && !d.timers[0].at.After(now) {
+		due = append(due, heap.Pop(&d.timers).(*timer).item) // nolint:errcheck
+	}
+	return due
+}
+
+// next returns the time of the earliest job.
+func (d *delayed) next() (time.Time, bool) {
+	if len(d.timers) == 0 {
+		return time.Time{}, false
+	}
+	return d.timers[0].at, true
+}
+
+// remove deletes a job by its id and returns it.
+func (d *delayed) remove(id job.ID) (*item, bool) {
+	for i, t := range d.timers {
+		if t.item.job.ID() == id {
+			heap.Remove(&d.timers, i)
+			return t.item, true
+		}
+	}
+	return nil, false
+}
+
+// clear removes all jobs and returns them.
+func (d *delayed) clear() []*item {
+	items := make([]*item, len(d.timers))
+	for i, t := range d.timers {
+		items[i] = t.item
+	}
+	d.timers = nil
+	return items
+}
+
+func (d *delayed) len() int {
+	return len(d.timers)
+}
+
+// timers implements heap.Interface.
+type timers []*timer
+
+func (t timers) Len() int {
+	return len(t)
+}
+
+func (t timers) Less(i, j int) bool {
+	if !t[i].at.Equal(t[j].at) {
+		return t[i].at.Before(t[j].at)
+	}
+	return t[i].seq < t[j].seq
+}
+
+func (t timers) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+}
+
+func (t *timers) Push(x interface{}) {
+	*t = append(*t, x.(*timer)) // nolint:errcheck
+}
+
+func (t *timers) Pop() interface{} {
+	old := *t
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*t = old[:n-1]
+	return x
+}
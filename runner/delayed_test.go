@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelayed_PopDue(t *testing.T) {
+	d := &delayed{}
+	now := time.Now()
+
+	later := newTestJob(t)
+	first := newTestJob(t)
+	second := newTestJob(t)
+
+	d.push(&item{job: later}, now.Add(time.Minute))
+	d.push(&item{job: first, priority: PriorityHigh}, now)
+	d.push(&item{job: second, priority: PriorityLow}, now)
+
+	next, ok := d.next()
+	assert.True(t, ok)
+	assert.Equal(t, now, next)
+
+	due := d.popDue(now)
+	assert.Len(t, due, 2)
+	assert.Equal(t, first.ID(), due[0].job.ID())
+	assert.Equal(t, PriorityHigh, due[0].priority)
+	assert.Equal(t, second.ID(), due[1].job.ID())
+
+	assert.Empty(t, d.popDue(now))
+	assert.Equal(t, 1, d.len())
+}
+
+func TestDelayed_Remove(t *testing.T) {
+	d := &delayed{}
+	now := time.Now()
+
+	j1 := newTestJob(t)
+	j2 := newTestJob(t)
+	d.push(&item{job: j1}, now.Add(time.Second))
+	d.push(&item{job: j2}, now.Add(time.Minute))
+
+	removed, ok := d.remove(j1.ID())
+	assert.True(t, ok)
+	assert.Equal(t, j1.ID(), removed.job.ID())
+
+	_, ok = d.remove(j1.ID())
+	assert.False(t, ok)
+
+	assert.Len(t, d.clear(), 1)
+	assert.Equal(t, 0, d.len())
+}
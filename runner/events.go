@@ -0,0 +1,200 @@
+package runner
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+const eventBuffer = 256
+
+// EventType is the kind of transition an event reports.
+type EventType int
+
+const (
+	// EventEnqueued is sent when a job is given to the runner, including delayed jobs.
+	EventEnqueued EventType = iota
+	// EventStarted is sent when a worker starts running a job.
+	EventStarted
+	// EventSucceeded is sent when a job finished without error.
+	EventSucceeded
+	// EventFailed is sent when a job finished with error or exceeded its deadline.
+	EventFailed
+	// EventCanceled is sent when a job was canceled, including delayed jobs which never ran.
+	EventCanceled
+	// EventScaledUp is sent when workers are added.
+	EventScaledUp
+	// EventScaledDown is sent when workers are asked to stop.
+	EventScaledDown
+	// EventStopped is sent when the runner is stopped.
+	EventStopped
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventEnqueued:
+		return "enqueued"
+	case EventStarted:
+		return "started"
+	case EventSucceeded:
+		return "succeeded"
+	case EventFailed:
+		return "failed"
+	case EventCanceled:
+		return "canceled"
+	case EventScaledUp:
+		return "scaled up"
+	case EventScaledDown:
+		return "scaled down"
+	case EventStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a transition of a job or of the runner.
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	// JobID is set for job events.
+	JobID job.ID
+	// Duration is how long a started job waited in the queue, or how long a finished job ran.
+	// Zero for jobs which never ran.
+	Duration time.Duration
+	// Err is the error of a failed job or the cause of a canceled job.
+	Err error
+
+	// Concurrency is the number of workers after scaling.
+	Concurrency int
+}
+
+// events sends events to subscribers without blocking.
+// An event is dropped for a subscriber whose buffer is full.
+type events struct {
+	subscribers map[<-chan Event]chan Event
+	buffer      int
+	dropped     atomic.Uint64
+	// totals counts the job events by type, whether subscribers received them or not
+	totals [EventCanceled + 1]atomic.Uint64
+	lock   sync.RWMutex
+}
+
+func (e *events) emit(event Event) {
+	if event.Type <= EventCanceled {
+		e.totals[event.Type].Add(1)
+	}
+
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	for _, ch := range e.subscribers {
+		select {
+		case ch <- event:
+		default:
+			e.dropped.Add(1)
+		}
+	}
+}
+
+func (e *events) subscribe() <-chan Event {
+	ch := make(chan Event, e.buffer)
+
+	e.lock.Lock()
+	e.subscribers[ch] = ch
+	e.lock.Unlock()
+
+	return ch
+}
+
+func (e *events) unsubscribe(ch <-chan Event) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if sub, ok := e.subscribers[ch]; ok {
+		delete(e.subscribers, ch)
+		close(sub)
+	}
+}
+
+func newEvents(buffer int) *events {
+	return &events{
+		subscribers: make(map[<-chan Event]chan Event),
+		buffer:      buffer,
+	}
+}
+
+// Totals are the numbers of jobs since the runner was created, by what happened to them.
+type Totals struct {
+	Enqueued  uint64
+	Started   uint64
+	Succeeded uint64
+	Failed    uint64
+	Canceled  uint64
+}
+
+// Totals returns the numbers of job events sent since the runner was created.
+// Unlike the events received by subscribers, none is missed.
+func (r *Runner) Totals() Totals {
+	return Totals{
+		Enqueued:  r.events.totals[EventEnqueued].Load(),
+		Started:   r.events.totals[EventStarted].Load(),
+		Succeeded: r.events.totals[EventSucceeded].Load(),
+		Failed:    r.events.totals[EventFailed].Load(),
+		Canceled:  r.events.totals[EventCanceled].Load(),
+	}
+}
+
+// Subscribe returns a channel receiving all events from now on.
+// Events are never waited for: if the channel buffer (Config.EventBuffer) is full,
+// the event is dropped for this subscriber and counted in Status.DroppedEvents.
+func (r *Runner) Subscribe() <-chan Event {
+	return r.events.subscribe()
+}
+
+// Unsubscribe stops sending events to a channel returned by Subscribe and closes it.
+func (r *Runner) Unsubscribe(ch <-chan Event) {
+	r.events.unsubscribe(ch)
+}
+
+// Hook calls given function for every event, on its own routine and in the order of the events.
+// It follows the drop policy of Subscribe. The returned function removes the hook.
+func (r *Runner) Hook(hook func(Event)) (remove func()) {
+	ch := r.Subscribe()
+
+	go func() {
+		for event := range ch {
+			hook(event)
+		}
+	}()
+
+	return func() {
+		r.Unsubscribe(ch)
+	}
+}
+
+// emit sends a job event.
+func (r *Runner) emit(t EventType, id job.ID, d time.Duration, err error) {
+	r.events.emit(Event{
+		Type:     t,
+		Time:     r.clock.Now(),
+		JobID:    id,
+		Duration: d,
+		Err:      err,
+	})
+}
+
+// eventType returns the event type of a job finished in given state.
+func eventType(state State) EventType {
+	switch state {
+	case StateSucceeded:
+		return EventSucceeded
+	case StateCanceled:
+		return EventCanceled
+	default:
+		return EventFailed
+	}
+}
@@ -0,0 +1,152 @@
+package runner_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Subscribe(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	events := r.Subscribe()
+	r.Start()
+
+	succeeded, err := job.New(&task{})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Enqueue(succeeded))
+
+	assertEvent(t, events, runner.EventEnqueued, succeeded.ID())
+	assertEvent(t, events, runner.EventStarted, succeeded.ID())
+	assertEvent(t, events, runner.EventSucceeded, succeeded.ID())
+
+	failed, err := job.New(&failingTask{failures: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Enqueue(failed))
+
+	assertEvent(t, events, runner.EventEnqueued, failed.ID())
+	assertEvent(t, events, runner.EventStarted, failed.ID())
+	event := assertEvent(t, events, runner.EventFailed, failed.ID())
+	assert.EqualError(t, event.Err, "failed")
+	assert.False(t, event.Time.IsZero())
+
+	delayed, err := job.New(&cancelableTask{myTask: &myTask{}})
+	assert.NoError(t, err)
+	assert.NoError(t, r.EnqueueAfter(time.Hour, delayed))
+	r.Cancel(delayed.ID())
+
+	assertEvent(t, events, runner.EventEnqueued, delayed.ID())
+	event = assertEvent(t, events, runner.EventCanceled, delayed.ID())
+	assert.Equal(t, runner.ErrCanceled, event.Err)
+	assert.Zero(t, event.Duration)
+
+	r.ScaleUp(2)
+	event = assertEvent(t, events, runner.EventScaledUp, "")
+	assert.Equal(t, 3, event.Concurrency)
+
+	r.ScaleDown(1)
+	event = assertEvent(t, events, runner.EventScaledDown, "")
+	assert.Equal(t, 2, event.Concurrency)
+
+	r.Stop()
+	assertEvent(t, events, runner.EventStopped, "")
+
+	r.Unsubscribe(events)
+	_, open := <-events
+	assert.False(t, open)
+	assert.Zero(t, r.Status().DroppedEvents)
+}
+
+func TestRunner_SubscribeDropsEvents(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1, EventBuffer: 1})
+	events := r.Subscribe()
+
+	r.ScaleUp(1)
+	r.ScaleUp(1)
+	r.ScaleUp(1)
+
+	assert.Equal(t, uint64(2), r.Status().DroppedEvents)
+
+	event := <-events
+	assert.Equal(t, runner.EventScaledUp, event.Type)
+	assert.Equal(t, 2, event.Concurrency)
+
+	r.Stop()
+}
+
+func TestRunner_Totals(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1, EventBuffer: 1})
+	r.Start()
+	defer r.Stop()
+
+	// A subscriber which does not read misses events
+	r.Subscribe()
+
+	succeeded, err := job.New(&task{})
+	assert.NoError(t, err)
+	failed, err := job.New(&failingTask{failures: 1})
+	assert.NoError(t, err)
+	canceled, err := job.New(&task{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Enqueue(succeeded, failed))
+	assert.NoError(t, r.EnqueueAfter(time.Hour, canceled))
+	r.Cancel(canceled.ID())
+	for totals := r.Totals(); totals.Succeeded+totals.Failed != 2; totals = r.Totals() {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(t, runner.Totals{Enqueued: 3, Started: 2, Succeeded: 1, Failed: 1, Canceled: 1}, r.Totals())
+	assert.NotZero(t, r.Status().DroppedEvents)
+}
+
+func TestRunner_Hook(t *testing.T) {
+	r := runner.New(runner.Config{})
+
+	received := make(chan runner.Event)
+	remove := r.Hook(func(event runner.Event) {
+		received <- event
+	})
+
+	r.ScaleUp(1)
+	assert.Equal(t, runner.EventScaledUp, (<-received).Type)
+
+	remove()
+	r.ScaleUp(1)
+
+	select {
+	case event := <-received:
+		t.Errorf("unexpected event after hook was removed: %v", event.Type)
+	case <-time.After(time.Millisecond * 10):
+	}
+
+	r.Stop()
+}
+
+func TestEventType_String(t *testing.T) {
+	assert.Equal(t, "enqueued", runner.EventEnqueued.String())
+	assert.Equal(t, "started", runner.EventStarted.String())
+	assert.Equal(t, "succeeded", runner.EventSucceeded.String())
+	assert.Equal(t, "failed", runner.EventFailed.String())
+	assert.Equal(t, "canceled", runner.EventCanceled.String())
+	assert.Equal(t, "scaled up", runner.EventScaledUp.String())
+	assert.Equal(t, "scaled down", runner.EventScaledDown.String())
+	assert.Equal(t, "stopped", runner.EventStopped.String())
+	assert.Equal(t, "unknown", runner.EventType(100).String())
+}
+
+func assertEvent(t *testing.T, events <-chan runner.Event, typ runner.EventType, id job.ID) runner.Event {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		assert.Equal(t, typ, event.Type)
+		assert.Equal(t, id, event.JobID)
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("expected %s event", typ)
+		return runner.Event{}
+	}
+}
@@ -0,0 +1,82 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DuplicatePolicy tells what happens to a job enqueued with the idempotency key of another job
+// which is queued, delayed or running, or which succeeded within the idempotency window.
+type DuplicatePolicy int
+
+const (
+	// RejectDuplicates fails enqueuing the duplicate with a *DuplicateError.
+	RejectDuplicates DuplicatePolicy = iota
+	// CoalesceDuplicates drops the duplicate without error. Its OnDone callback is called
+	// with the state of the original job when it finishes, or right away if it already finished.
+	// A duplicate without an OnDone callback would be dropped unnoticed, so it fails with a *DuplicateError.
+	CoalesceDuplicates
+)
+
+// ErrDuplicate is matched by every *DuplicateError.
+var ErrDuplicate = errors.New("duplicate job")
+
+// DuplicateError is returned for a job with the idempotency key of another job.
+type DuplicateError struct {
+	Key string
+	// Original is the state of the job holding the key when the duplicate was enqueued.
+	Original JobInfo
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("idempotency key %q is held by %s job %s", e.Key, e.Original.State, e.Original.ID)
+}
+
+// Is tells if given error is ErrDuplicate.
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrDuplicate
+}
+
+// deduplicate checks if a job is a duplicate. It returns true if the job must not be enqueued,
+// with the error to return if the duplicate is rejected. It must be called with the lock held.
+func (r *Runner) deduplicate(it *item) (bool, error) {
+	key := it.job.IdempotencyKey()
+	if key == "" {
+		return false, nil
+	}
+
+	coalesce := r.duplicates == CoalesceDuplicates && it.onDone != nil
+
+	var onDone func(JobInfo)
+	if coalesce {
+		onDone = it.onDone
+	}
+
+	original, ok := r.registry.claim(key, onDone, r.clock.Now())
+	if !ok {
+		return false, nil
+	}
+
+	if !coalesce {
+		return true, &DuplicateError{Key: key, Original: original}
+	}
+
+	// The callbacks are not called with the lock held
+	go func() {
+		if it.done != nil {
+			it.done()
+		}
+		if original.State.IsFinished() {
+			it.onDone(original)
+		}
+	}()
+
+	return true, nil
+}
+
+// coalesced calls the callbacks of the duplicates coalesced into a job which finished.
+func coalesced(waiters []func(JobInfo), info JobInfo) {
+	for _, onDone := range waiters {
+		onDone(info)
+	}
+}
@@ -0,0 +1,29 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_FinishPrunesKeys(t *testing.T) {
+	g := newRegistry(retainFinished, 0, time.Minute)
+	now := time.Now()
+
+	keyed, err := job.New(noopTask{}, job.WithIdempotencyKey("request"))
+	assert.NoError(t, err)
+	other := newTestJob(t)
+
+	g.enqueued(&item{job: keyed}, now, now)
+	g.finish(keyed.ID(), StateSucceeded, nil, nil, 1, now)
+	assert.Len(t, g.keys, 1)
+
+	// The key expired, it is released by the next job which finishes
+	now = now.Add(time.Minute + time.Second)
+	g.enqueued(&item{job: other}, now, now)
+	g.finish(other.ID(), StateSucceeded, nil, nil, 1, now)
+	assert.Empty(t, g.keys)
+	assert.Empty(t, g.succeeded)
+}
@@ -0,0 +1,138 @@
+package runner_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_RejectDuplicates(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+	defer r.Stop()
+
+	blocking := &blockingTask{release: make(chan struct{})}
+	original, err := job.New(blocking, job.WithIdempotencyKey("request"))
+	assert.NoError(t, err)
+	duplicate, err := job.New(&task{}, job.WithIdempotencyKey("request"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Enqueue(original))
+	waitState(t, r, original.ID(), runner.StateRunning)
+
+	err = r.Enqueue(duplicate)
+	assert.ErrorIs(t, err, runner.ErrDuplicate)
+	var duplicateErr *runner.DuplicateError
+	assert.True(t, errors.As(err, &duplicateErr))
+	assert.Equal(t, "request", duplicateErr.Key)
+	assert.Equal(t, original.ID(), duplicateErr.Original.ID)
+	assert.Equal(t, runner.StateRunning, duplicateErr.Original.State)
+
+	info, ok := r.GetByIdempotencyKey("request")
+	assert.True(t, ok)
+	assert.Equal(t, original.ID(), info.ID)
+	assert.Equal(t, "request", info.IdempotencyKey)
+
+	_, ok = r.Get(duplicate.ID())
+	assert.False(t, ok)
+
+	close(blocking.release)
+	waitState(t, r, original.ID(), runner.StateSucceeded)
+
+	// Without an idempotency window, the key is released when the job finishes
+	_, ok = r.GetByIdempotencyKey("request")
+	assert.False(t, ok)
+	assert.NoError(t, r.Enqueue(duplicate))
+	waitState(t, r, duplicate.ID(), runner.StateSucceeded)
+}
+
+func TestRunner_IdempotencyWindow(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	r := runner.New(runner.Config{Concurrency: 1, Clock: c, IdempotencyWindow: time.Minute})
+	r.Start()
+	defer r.Stop()
+
+	succeeded, err := job.New(&task{}, job.WithIdempotencyKey("succeeded"))
+	assert.NoError(t, err)
+	failed, err := job.New(&failingTask{failures: 1}, job.WithIdempotencyKey("failed"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Enqueue(succeeded, failed))
+	waitState(t, r, succeeded.ID(), runner.StateSucceeded)
+	waitState(t, r, failed.ID(), runner.StateFailed)
+
+	again, err := job.New(&task{}, job.WithIdempotencyKey("succeeded"))
+	assert.NoError(t, err)
+
+	var duplicateErr *runner.DuplicateError
+	assert.True(t, errors.As(r.Enqueue(again), &duplicateErr))
+	assert.Equal(t, runner.StateSucceeded, duplicateErr.Original.State)
+
+	// A failed job releases its key
+	retried, err := job.New(&task{}, job.WithIdempotencyKey("failed"))
+	assert.NoError(t, err)
+	assert.NoError(t, r.Enqueue(retried))
+
+	c.Advance(time.Minute + time.Second)
+	assert.NoError(t, r.Enqueue(again))
+	waitState(t, r, again.ID(), runner.StateSucceeded)
+}
+
+func TestRunner_CoalesceDuplicates(t *testing.T) {
+	r := runner.New(runner.Config{
+		Concurrency:       1,
+		Duplicates:        runner.CoalesceDuplicates,
+		IdempotencyWindow: time.Hour,
+	})
+	r.Start()
+	defer r.Stop()
+
+	done := make(chan runner.JobInfo, 3)
+	opts := runner.EnqueueOptions{
+		OnDone: func(info runner.JobInfo) {
+			done <- info
+		},
+	}
+
+	blocking := &blockingTask{release: make(chan struct{})}
+	original, err := job.New(blocking, job.WithIdempotencyKey("request"))
+	assert.NoError(t, err)
+	duplicate, err := job.New(&task{}, job.WithIdempotencyKey("request"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.EnqueueWithOptions(opts, original))
+	assert.NoError(t, r.EnqueueWithOptions(opts, duplicate))
+
+	// A duplicate without a callback is reported
+	unnoticed, err := job.New(&task{}, job.WithIdempotencyKey("request"))
+	assert.NoError(t, err)
+	err = r.Enqueue(unnoticed)
+	assert.ErrorIs(t, err, runner.ErrDuplicate)
+	var duplicateErr *runner.DuplicateError
+	assert.True(t, errors.As(err, &duplicateErr))
+	assert.Equal(t, original.ID(), duplicateErr.Original.ID)
+
+	close(blocking.release)
+
+	for i := 0; i < 2; i++ {
+		info := <-done
+		assert.Equal(t, original.ID(), info.ID)
+		assert.Equal(t, runner.StateSucceeded, info.State)
+	}
+
+	// After the original finished, the duplicate gets its state right away
+	late, err := job.New(&task{}, job.WithIdempotencyKey("request"))
+	assert.NoError(t, err)
+	assert.NoError(t, r.EnqueueWithOptions(opts, late))
+
+	info := <-done
+	assert.Equal(t, original.ID(), info.ID)
+
+	_, ok := r.Get(late.ID())
+	assert.False(t, ok)
+}
@@ -0,0 +1,70 @@
+package runner_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Panic(t *testing.T) {
+	var handled []*job.PanicError
+	var lock sync.Mutex
+
+	r := runner.New(runner.Config{
+		Concurrency: 1,
+		DeadLetters: &panicDeadLetterQueue{runner.NewMemoryDeadLetterQueue(0)},
+		PanicHandler: func(err *job.PanicError) {
+			lock.Lock()
+			handled = append(handled, err)
+			lock.Unlock()
+		},
+	})
+	r.Start()
+	defer r.Stop()
+
+	panicking, err := job.New(&panicTask{})
+	assert.NoError(t, err)
+
+	var count int32
+	next, err := job.New(&countTask{count: &count})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Enqueue(panicking, next))
+
+	// The only worker survived
+	for atomic.LoadInt32(&count) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// The task panic and the dead letter queue panic
+	assert.Len(t, handled, 2)
+	assert.Equal(t, "boom", handled[0].Value)
+	assert.Equal(t, "dead letter queue", handled[1].Value)
+}
+
+type panicTask struct{}
+
+func (panicTask) Run(*job.Job) (interface{}, error) {
+	panic("boom")
+}
+
+type panicDeadLetterQueue struct {
+	*runner.MemoryDeadLetterQueue
+}
+
+func (q *panicDeadLetterQueue) Put(letter runner.DeadLetter) error {
+	var panicErr *job.PanicError
+	if errors.As(letter.Err, &panicErr) {
+		panic("dead letter queue")
+	}
+	return q.MemoryDeadLetterQueue.Put(letter)
+}
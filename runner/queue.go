@@ -0,0 +1,187 @@
+package runner
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+// Priority of a job in the runner queue. Jobs with higher priority are run first.
+type Priority int
+
+const (
+	// PriorityLow is for background work which can wait.
+	PriorityLow Priority = -1
+	// PriorityNormal is the priority of jobs enqueued with Enqueue.
+	PriorityNormal Priority = 0
+	// PriorityHigh is for work which must run before anything else.
+	PriorityHigh Priority = 1
+)
+
+// Queue holds the jobs waiting for a worker, in the order they are run.
+// The runner calls it with its lock held. A durable queue keeps the jobs until they are acknowledged,
+// so they can be run again after a restart.
+type Queue interface {
+	// Push adds a job.
+	Push(QueuedJob) error
+	// Pop removes the next job to run.
+	Pop() (QueuedJob, bool)
+	// Ack is called when a popped job is done, so it does not have to be kept anymore.
+	Ack(QueuedJob) error
+	// Len returns the number of jobs waiting.
+	Len() int
+	// Counts returns the number of jobs waiting by priority.
+	Counts() map[Priority]int
+}
+
+// QueuedJob is a job waiting in the queue.
+type QueuedJob struct {
+	Job        *job.Job
+	Priority   Priority
+	EnqueuedAt time.Time
+	// Key is the rate limiting key of the job
+	Key string
+
+	// item is kept by the runner for the jobs it enqueued, nil for jobs restored by a durable queue
+	item *item
+}
+
+// item is a job given to the runner.
+type item struct {
+	job      *job.Job
+	priority Priority
+	key      string
+	// done is called after the job was run
+	done     func()
+	onDone   func(JobInfo)
+	enqueued time.Time
+}
+
+func (it *item) queued() QueuedJob {
+	return QueuedJob{
+		Job:        it.job,
+		Priority:   it.priority,
+		EnqueuedAt: it.enqueued,
+		Key:        it.key,
+		item:       it,
+	}
+}
+
+// itemOf returns the runner item of a queued job.
+func itemOf(qj QueuedJob) *item {
+	if qj.item != nil {
+		return qj.item
+	}
+	return &item{job: qj.Job, priority: qj.Priority, key: qj.Key, enqueued: qj.EnqueuedAt}
+}
+
+// entry is a job in the memory queue.
+type entry struct {
+	QueuedJob
+	seq  uint64
+	rank int64
+}
+
+// queue is the memory Queue. It holds pending jobs ordered by priority, FIFO inside the same priority.
+// If aging is set, a job gains one priority level for every aging interval it waited,
+// so low priority jobs are eventually run even if higher priority jobs keep coming.
+type queue struct {
+	items   items
+	aging   time.Duration
+	epoch   time.Time
+	seq     uint64
+	pending map[Priority]int
+}
+
+func (q *queue) Push(qj QueuedJob) error {
+	q.seq++
+
+	e := &entry{
+		QueuedJob: qj,
+		seq:       q.seq,
+		rank:      int64(qj.Priority),
+	}
+
+	// The aged priority of a job is priority + waited/aging. When comparing two jobs at the same
+	// moment, the waited time of both grows equally, so the order can be computed once at push.
+	if q.aging > 0 {
+		e.rank = int64(qj.Priority)*int64(q.aging) - int64(qj.EnqueuedAt.Sub(q.epoch))
+	}
+
+	heap.Push(&q.items, e)
+	q.pending[qj.Priority]++
+
+	return nil
+}
+
+func (q *queue) Pop() (QueuedJob, bool) {
+	if len(q.items) == 0 {
+		return QueuedJob{}, false
+	}
+
+	e := heap.Pop(&q.items).(*entry) // nolint:errcheck
+
+	q.pending[e.Priority]--
+	if q.pending[e.Priority] == 0 {
+		delete(q.pending, e.Priority)
+	}
+
+	return e.QueuedJob, true
+}
+
+// Ack does nothing, as jobs are not kept after they are popped.
+func (q *queue) Ack(QueuedJob) error {
+	return nil
+}
+
+func (q *queue) Len() int {
+	return len(q.items)
+}
+
+func (q *queue) Counts() map[Priority]int {
+	counts := make(map[Priority]int, len(q.pending))
+	for p, c := range q.pending {
+		counts[p] = c
+	}
+	return counts
+}
+
+func newQueue(aging time.Duration, epoch time.Time) *queue {
+	return &queue{
+		aging:   aging,
+		epoch:   epoch,
+		pending: make(map[Priority]int),
+	}
+}
+
+// items implements heap.Interface.
+type items []*entry
+
+func (it items) Len() int {
+	return len(it)
+}
+
+func (it items) Less(i, j int) bool {
+	if it[i].rank != it[j].rank {
+		return it[i].rank > it[j].rank
+	}
+	return it[i].seq < it[j].seq
+}
+
+func (it items) Swap(i, j int) {
+	it[i], it[j] = it[j], it[i]
+}
+
+func (it *items) Push(x interface{}) {
+	*it = append(*it, x.(*entry)) // nolint:errcheck
+}
+
+func (it *items) Pop() interface{} {
+	old := *it
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*it = old[:n-1]
+	return x
+}
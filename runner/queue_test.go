@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue_PriorityOrder(t *testing.T) {
+	now := time.Now()
+	q := newQueue(0, now)
+
+	low := newTestJob(t)
+	normal1 := newTestJob(t)
+	high := newTestJob(t)
+	normal2 := newTestJob(t)
+
+	assert.NoError(t, q.Push(QueuedJob{Job: low, Priority: PriorityLow, EnqueuedAt: now}))
+	assert.NoError(t, q.Push(QueuedJob{Job: normal1, Priority: PriorityNormal, EnqueuedAt: now}))
+	assert.NoError(t, q.Push(QueuedJob{Job: high, Priority: PriorityHigh, EnqueuedAt: now}))
+	assert.NoError(t, q.Push(QueuedJob{Job: normal2, Priority: PriorityNormal, EnqueuedAt: now}))
+
+	assert.Equal(t, map[Priority]int{PriorityLow: 1, PriorityNormal: 2, PriorityHigh: 1}, q.Counts())
+
+	for _, expected := range []*job.Job{high, normal1, normal2, low} {
+		it, ok := q.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, expected.ID(), it.Job.ID())
+	}
+
+	_, ok := q.Pop()
+	assert.False(t, ok)
+	assert.Empty(t, q.Counts())
+}
+
+func TestQueue_Aging(t *testing.T) {
+	aging := time.Second
+	now := time.Now()
+	q := newQueue(aging, now)
+
+	low := newTestJob(t)
+	high := newTestJob(t)
+	newerHigh := newTestJob(t)
+
+	// low is two levels below high, so it is ahead of high jobs enqueued more than two intervals after it
+	assert.NoError(t, q.Push(QueuedJob{Job: low, Priority: PriorityLow, EnqueuedAt: now}))
+	assert.NoError(t, q.Push(QueuedJob{Job: high, Priority: PriorityHigh, EnqueuedAt: now.Add(aging)}))
+	assert.NoError(t, q.Push(QueuedJob{Job: newerHigh, Priority: PriorityHigh, EnqueuedAt: now.Add(aging * 3)}))
+
+	for _, expected := range []*job.Job{high, low, newerHigh} {
+		it, ok := q.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, expected.ID(), it.Job.ID())
+	}
+}
+
+func TestRunner_RestoredQueue(t *testing.T) {
+	q := &ackQueue{queue: newQueue(0, time.Now())}
+	restored := newTestJob(t)
+	assert.NoError(t, q.Push(QueuedJob{Job: restored, Priority: PriorityHigh, EnqueuedAt: time.Now()}))
+
+	r := New(Config{Concurrency: 1, Queue: q})
+	assert.Equal(t, map[Priority]int{PriorityHigh: 1}, r.Status().PendingJobs)
+
+	r.Start()
+	defer r.Stop()
+
+	enqueued := newTestJob(t)
+	assert.NoError(t, r.Enqueue(enqueued))
+
+	for _, id := range []job.ID{restored.ID(), enqueued.ID()} {
+		for {
+			info, ok := r.Get(id)
+			if ok && info.State == StateSucceeded {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	assert.Equal(t, []job.ID{restored.ID(), enqueued.ID()}, q.acked)
+}
+
+// ackQueue records acknowledged jobs.
+type ackQueue struct {
+	*queue
+	acked []job.ID
+}
+
+func (q *ackQueue) Ack(qj QueuedJob) error {
+	q.acked = append(q.acked, qj.Job.ID())
+	return nil
+}
+
+type noopTask struct{}
+
+func (noopTask) Run(*job.Job) (interface{}, error) {
+	return nil, nil
+}
+
+func newTestJob(t *testing.T) *job.Job {
+	j, err := job.New(noopTask{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
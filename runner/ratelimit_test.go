@@ -0,0 +1,76 @@
+package runner_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/ratelimit"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_RateLimiter(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	limiter := ratelimit.New(ratelimit.Config{PerKey: ratelimit.Limit{Rate: 1}, Clock: c})
+
+	r := runner.New(runner.Config{Concurrency: 2, Clock: c, RateLimiter: limiter})
+	r.Start()
+	defer r.Stop()
+
+	first, err := job.New(&task{})
+	assert.NoError(t, err)
+	second, err := job.New(&task{})
+	assert.NoError(t, err)
+	other, err := job.New(&task{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.EnqueueWithOptions(runner.EnqueueOptions{Key: "tenant"}, first, second))
+
+	waitState(t, r, first.ID(), runner.StateSucceeded)
+	waitThrottled(t, r, 1)
+	info, _ := r.Get(second.ID())
+	assert.Equal(t, runner.StateQueued, info.State)
+
+	// Another key is not limited by the waiting job
+	assert.NoError(t, r.EnqueueWithOptions(runner.EnqueueOptions{Key: "other"}, other))
+	waitState(t, r, other.ID(), runner.StateSucceeded)
+
+	for c.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	c.Advance(time.Second)
+
+	waitState(t, r, second.ID(), runner.StateSucceeded)
+	waitThrottled(t, r, 0)
+}
+
+func TestRunner_RateLimiterCancel(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	limiter := ratelimit.New(ratelimit.Config{Global: ratelimit.Limit{Rate: 1}, Clock: c})
+	assert.True(t, limiter.Allow(""))
+
+	r := runner.New(runner.Config{Concurrency: 1, Clock: c, RateLimiter: limiter})
+	r.Start()
+	defer r.Stop()
+
+	waiting, err := job.NewContext(&contextTask{started: make(chan struct{})})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Enqueue(waiting))
+
+	waitThrottled(t, r, 1)
+	r.Cancel(waiting.ID())
+
+	info := waitState(t, r, waiting.ID(), runner.StateCanceled)
+	assert.ErrorIs(t, info.Err, runner.ErrCanceled)
+	waitThrottled(t, r, 0)
+}
+
+func waitThrottled(t *testing.T, r *runner.Runner, count int) {
+	t.Helper()
+
+	for r.Status().ThrottledJobs != count {
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,330 @@
+package runner
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+const retainFinished = 1024
+
+// State is the lifecycle state of a job in the runner.
+type State int
+
+const (
+	// StateQueued is a job waiting in the queue for a worker.
+	StateQueued State = iota
+	// StateScheduled is a job waiting for its time to be enqueued.
+	StateScheduled
+	// StateRunning is a job run by a worker.
+	StateRunning
+	// StateSucceeded is a job which finished without error.
+	StateSucceeded
+	// StateFailed is a job which finished with error or exceeded its deadline.
+	StateFailed
+	// StateCanceled is a job which was canceled.
+	StateCanceled
+)
+
+func (s State) String() string {
+	switch s {
+	case StateQueued:
+		return "queued"
+	case StateScheduled:
+		return "scheduled"
+	case StateRunning:
+		return "running"
+	case StateSucceeded:
+		return "succeeded"
+	case StateFailed:
+		return "failed"
+	case StateCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// IsFinished returns true if the job is done.
+func (s State) IsFinished() bool {
+	return s >= StateSucceeded
+}
+
+// JobInfo is a snapshot of a job known by the runner.
+type JobInfo struct {
+	ID       job.ID
+	State    State
+	Priority Priority
+
+	// IdempotencyKey is the idempotency key of the job, empty if it has none.
+	IdempotencyKey string
+
+	// EnqueuedAt is when the job was given to the runner.
+	EnqueuedAt time.Time
+	// ScheduledAt is when a delayed job is due. Zero if the job was not delayed.
+	ScheduledAt time.Time
+	StartedAt   time.Time
+	FinishedAt  time.Time
+
+	// Result, Err and Attempts are set when the job is finished.
+	// Err is the cancellation cause if the job was canceled and the task returned no error.
+	Result   interface{}
+	Err      error
+	Attempts int
+}
+
+// Filter selects jobs returned by List.
+type Filter func(JobInfo) bool
+
+// InState selects jobs in any of given states.
+func InState(states ...State) Filter {
+	return func(info JobInfo) bool {
+		for _, s := range states {
+			if info.State == s {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// registry keeps the state of the jobs which are not finished and of the last finished jobs.
+type registry struct {
+	jobs     map[job.ID]*JobInfo
+	finished []job.ID
+	retain   int
+	maxAge   time.Duration
+	// keys are the jobs holding idempotency keys
+	keys map[string]*JobInfo
+	// succeeded are the jobs which hold their keys after they finished, for the idempotency window
+	succeeded []*JobInfo
+	window    time.Duration
+	// waiters are the callbacks of the duplicates coalesced into a job
+	waiters map[job.ID][]func(JobInfo)
+	lock    sync.Mutex
+}
+
+func (g *registry) enqueued(it *item, at, now time.Time) {
+	info := &JobInfo{
+		ID:             it.job.ID(),
+		State:          StateQueued,
+		Priority:       it.priority,
+		IdempotencyKey: it.job.IdempotencyKey(),
+		EnqueuedAt:     now,
+	}
+	if at.After(now) {
+		info.State = StateScheduled
+		info.ScheduledAt = at
+	}
+
+	g.lock.Lock()
+	// A job enqueued again (e.g. requeued) is not finished anymore
+	g.forget(info.ID)
+	g.jobs[info.ID] = info
+	if info.IdempotencyKey != "" {
+		g.keys[info.IdempotencyKey] = info
+	}
+	g.lock.Unlock()
+}
+
+func (g *registry) update(id job.ID, update func(*JobInfo)) {
+	g.lock.Lock()
+	if info, ok := g.jobs[id]; ok && !info.State.IsFinished() {
+		update(info)
+	}
+	g.lock.Unlock()
+}
+
+func (g *registry) due(id job.ID) {
+	g.update(id, func(info *JobInfo) {
+		info.State = StateQueued
+	})
+}
+
+func (g *registry) started(id job.ID, now time.Time) {
+	g.update(id, func(info *JobInfo) {
+		info.State = StateRunning
+		info.StartedAt = now
+	})
+}
+
+// finish marks a job as done and drops the oldest finished jobs which are over the retention limits.
+// It returns the callbacks of the duplicates coalesced into the job.
+func (g *registry) finish(
+	id job.ID, state State, result interface{}, err error, attempts int, now time.Time,
+) []func(JobInfo) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	waiters := g.waiters[id]
+	delete(g.waiters, id)
+
+	info, ok := g.jobs[id]
+	if !ok || info.State.IsFinished() {
+		return waiters
+	}
+
+	info.State = state
+	info.FinishedAt = now
+	info.Result = result
+	info.Err = err
+	info.Attempts = attempts
+
+	// Only a job which succeeded keeps its key, so failed work can be enqueued again
+	if key := info.IdempotencyKey; key != "" && g.keys[key] == info {
+		if state == StateSucceeded && g.window > 0 {
+			g.succeeded = append(g.succeeded, info)
+		} else {
+			delete(g.keys, key)
+		}
+	}
+
+	g.finished = append(g.finished, id)
+	g.prune(now)
+	g.pruneKeys(now)
+
+	return waiters
+}
+
+// claim returns the job holding given idempotency key, if any. If the job is not finished,
+// given callback is called when it finishes, as the callback of a duplicate coalesced into it.
+func (g *registry) claim(key string, onDone func(JobInfo), now time.Time) (JobInfo, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.pruneKeys(now)
+
+	info, ok := g.keys[key]
+	if !ok {
+		return JobInfo{}, false
+	}
+
+	if onDone != nil && !info.State.IsFinished() {
+		g.waiters[info.ID] = append(g.waiters[info.ID], onDone)
+	}
+	return *info, true
+}
+
+func (g *registry) get(id job.ID, now time.Time) (JobInfo, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.prune(now)
+
+	info, ok := g.jobs[id]
+	if !ok {
+		return JobInfo{}, false
+	}
+	return *info, true
+}
+
+func (g *registry) list(filter Filter, now time.Time) []JobInfo {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.prune(now)
+
+	list := make([]JobInfo, 0, len(g.jobs))
+	for _, info := range g.jobs {
+		if filter == nil || filter(*info) {
+			list = append(list, *info)
+		}
+	}
+
+	return list
+}
+
+// prune drops finished jobs over the count limit and older than the maximum age, oldest first.
+func (g *registry) prune(now time.Time) {
+	drop := 0
+	for ; drop < len(g.finished); drop++ {
+		if len(g.finished)-drop > g.retain {
+			continue
+		}
+		if g.maxAge > 0 && now.Sub(g.jobs[g.finished[drop]].FinishedAt) > g.maxAge {
+			continue
+		}
+		break
+	}
+
+	for _, id := range g.finished[:drop] {
+		delete(g.jobs, id)
+	}
+	g.finished = g.finished[drop:]
+}
+
+// pruneKeys releases the keys of the jobs which succeeded longer than the idempotency window ago.
+func (g *registry) pruneKeys(now time.Time) {
+	drop := 0
+	for ; drop < len(g.succeeded); drop++ {
+		info := g.succeeded[drop]
+		if now.Sub(info.FinishedAt) <= g.window {
+			break
+		}
+		if g.keys[info.IdempotencyKey] == info {
+			delete(g.keys, info.IdempotencyKey)
+		}
+	}
+	g.succeeded = g.succeeded[drop:]
+}
+
+// forget removes a job from the finished jobs.
+func (g *registry) forget(id job.ID) {
+	for i, finished := range g.finished {
+		if finished == id {
+			g.finished = append(g.finished[:i], g.finished[i+1:]...)
+			return
+		}
+	}
+}
+
+func newRegistry(retain int, maxAge, window time.Duration) *registry {
+	return &registry{
+		jobs:    make(map[job.ID]*JobInfo),
+		retain:  retain,
+		maxAge:  maxAge,
+		keys:    make(map[string]*JobInfo),
+		window:  window,
+		waiters: make(map[job.ID][]func(JobInfo)),
+	}
+}
+
+// Get returns the state of a job by its id. Finished jobs are kept within the retention limits.
+func (r *Runner) Get(id job.ID) (JobInfo, bool) {
+	return r.registry.get(id, r.clock.Now())
+}
+
+// GetByIdempotencyKey returns the state of the job holding given idempotency key: a job which is queued,
+// delayed or running, or which succeeded within the idempotency window.
+func (r *Runner) GetByIdempotencyKey(key string) (JobInfo, bool) {
+	if key == "" {
+		return JobInfo{}, false
+	}
+	return r.registry.claim(key, nil, r.clock.Now())
+}
+
+// List returns the state of the jobs selected by filter, in no particular order. Nil filter selects all jobs.
+func (r *Runner) List(filter Filter) []JobInfo {
+	return r.registry.list(filter, r.clock.Now())
+}
+
+// finishState returns the state of a finished job and its error.
+func finishState(j *job.Job, future *job.Future) (State, error) {
+	err := future.Error()
+
+	// A job which exceeded its deadline failed, even if it was canceled
+	if future.IsCanceled() && !errors.Is(err, job.ErrDeadlineExceeded) {
+		if err == nil {
+			err = j.Cause()
+		}
+		return StateCanceled, err
+	}
+
+	if err != nil {
+		return StateFailed, err
+	}
+
+	return StateSucceeded, nil
+}
@@ -0,0 +1,163 @@
+package runner_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Get(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	r := runner.New(runner.Config{Concurrency: 1, Clock: c})
+	r.Start()
+	defer r.Stop()
+
+	blocking := &blockingTask{release: make(chan struct{})}
+	running, err := job.New(blocking)
+	assert.NoError(t, err)
+
+	queued, err := job.New(&failingTask{failures: 1})
+	assert.NoError(t, err)
+
+	scheduled, err := job.New(&task{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Enqueue(running, queued))
+	assert.NoError(t, r.EnqueueAt(c.Now().Add(time.Minute), scheduled))
+
+	waitState(t, r, running.ID(), runner.StateRunning)
+
+	info, ok := r.Get(queued.ID())
+	assert.True(t, ok)
+	assert.Equal(t, runner.StateQueued, info.State)
+	assert.Equal(t, c.Now(), info.EnqueuedAt)
+	assert.True(t, info.StartedAt.IsZero())
+
+	info, ok = r.Get(scheduled.ID())
+	assert.True(t, ok)
+	assert.Equal(t, runner.StateScheduled, info.State)
+	assert.Equal(t, c.Now().Add(time.Minute), info.ScheduledAt)
+
+	_, ok = r.Get(job.ID("unknown"))
+	assert.False(t, ok)
+
+	close(blocking.release)
+
+	waitState(t, r, running.ID(), runner.StateSucceeded)
+	info = waitState(t, r, queued.ID(), runner.StateFailed)
+	assert.EqualError(t, info.Err, "failed")
+	assert.Equal(t, 1, info.Attempts)
+	assert.False(t, info.StartedAt.IsZero())
+	assert.False(t, info.FinishedAt.IsZero())
+
+	assert.Len(t, r.List(nil), 3)
+	assert.Len(t, r.List(runner.InState(runner.StateSucceeded, runner.StateFailed)), 2)
+
+	r.Cancel(scheduled.ID())
+	info = waitState(t, r, scheduled.ID(), runner.StateCanceled)
+	assert.Equal(t, runner.ErrCanceled, info.Err)
+}
+
+func TestRunner_GetCanceled(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+	defer r.Stop()
+
+	canceled, err := job.New(&cancelableTask{myTask: &myTask{}})
+	assert.NoError(t, err)
+	canceled.Cancel(nil)
+
+	assert.NoError(t, r.Enqueue(canceled))
+
+	info := waitState(t, r, canceled.ID(), runner.StateCanceled)
+	assert.Equal(t, job.ErrCanceled, info.Err)
+}
+
+func TestRunner_Retention(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	r := runner.New(runner.Config{
+		Concurrency:    1,
+		Clock:          c,
+		RetainFinished: 2,
+		RetainFor:      time.Minute,
+	})
+	r.Start()
+	defer r.Stop()
+
+	jobs := make([]*job.Job, 3)
+	for i := range jobs {
+		j, err := job.New(&task{})
+		assert.NoError(t, err)
+		jobs[i] = j
+
+		assert.NoError(t, r.Enqueue(j))
+		waitState(t, r, j.ID(), runner.StateSucceeded)
+	}
+
+	// Oldest was dropped by count
+	_, ok := r.Get(jobs[0].ID())
+	assert.False(t, ok)
+	assert.Len(t, r.List(nil), 2)
+
+	// All are dropped by age
+	c.Advance(time.Minute + time.Second)
+	assert.Empty(t, r.List(nil))
+}
+
+func TestState_String(t *testing.T) {
+	assert.Equal(t, "queued", runner.StateQueued.String())
+	assert.Equal(t, "scheduled", runner.StateScheduled.String())
+	assert.Equal(t, "running", runner.StateRunning.String())
+	assert.Equal(t, "succeeded", runner.StateSucceeded.String())
+	assert.Equal(t, "failed", runner.StateFailed.String())
+	assert.Equal(t, "canceled", runner.StateCanceled.String())
+	assert.Equal(t, "unknown", runner.State(100).String())
+}
+
+func waitState(t *testing.T, r *runner.Runner, id job.ID, state runner.State) runner.JobInfo {
+	t.Helper()
+
+	for {
+		info, ok := r.Get(id)
+		if !assert.True(t, ok) || info.State == state {
+			return info
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunner_OnDone(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+	defer r.Stop()
+
+	done := make(chan runner.JobInfo, 2)
+	opts := runner.EnqueueOptions{
+		Priority: runner.PriorityHigh,
+		OnDone: func(info runner.JobInfo) {
+			done <- info
+		},
+	}
+
+	succeeded, err := job.New(&task{})
+	assert.NoError(t, err)
+	failed, err := job.New(&failingTask{failures: 1})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.EnqueueWithOptions(opts, succeeded, failed))
+
+	info := <-done
+	assert.Equal(t, succeeded.ID(), info.ID)
+	assert.Equal(t, runner.StateSucceeded, info.State)
+	assert.Equal(t, runner.PriorityHigh, info.Priority)
+
+	info = <-done
+	assert.Equal(t, failed.ID(), info.ID)
+	assert.Equal(t, runner.StateFailed, info.State)
+	assert.EqualError(t, info.Err, "failed")
+	assert.False(t, info.FinishedAt.IsZero())
+}
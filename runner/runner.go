@@ -2,32 +2,137 @@
 package runner
 
 import (
+	"context"
 	"errors"
+	"runtime/debug"
 	"sync"
+	"time"
 
+	"github.com/andreiavrammsd/workexec/clock"
 	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/ratelimit"
 	"github.com/cespare/xxhash/v2"
 )
 
 const (
 	concurrency = 1024
 	queueSize   = 1024
+	leakTimeout = time.Second
+)
+
+var (
+	// ErrStopped is the cause jobs are canceled with when the runner is stopped.
+	ErrStopped = errors.New("runner was stopped")
+	// ErrCanceled is the cause jobs are canceled with by Cancel.
+	ErrCanceled = errors.New("canceled by runner")
+	// ErrNotRunning is returned by SetConcurrency when the runner is stopped or draining.
+	ErrNotRunning = errors.New("runner is not running")
 )
 
 // Config allows setup of runner.
 type Config struct {
 	Concurrency int
 	QueueSize   int
+
+	// Aging raises the priority of a pending job by one level for every interval it waited.
+	// Zero disables aging, so lower priority jobs wait as long as higher priority jobs are pending.
+	// It applies to the default queue.
+	Aging time.Duration
+
+	// Queue holds the pending jobs. Defaults to a memory queue.
+	// The jobs already in the queue are run when the runner is started.
+	Queue Queue
+
+	// Clock is used for delayed and scheduled jobs. Defaults to the system time.
+	Clock clock.Clock
+
+	// RateLimiter limits how often workers start jobs, globally and by the key of the jobs.
+	// A job waits for the limiter on a worker and can be canceled while waiting. Nil means no limit.
+	RateLimiter *ratelimit.Limiter
+
+	// DeadLetters receives the jobs which failed. Nil means failed jobs are forgotten.
+	DeadLetters DeadLetterQueue
+
+	// DeadLetterCanceled sends canceled jobs to the dead letter queue too.
+	DeadLetterCanceled bool
+
+	// JobTimeout cancels jobs which run longer than this duration. A job can have an earlier
+	// deadline of its own. Zero means jobs run as long as they need.
+	JobTimeout time.Duration
+
+	// LeakTimeout is how long a worker waits for a job which exceeded its deadline to stop.
+	// After it, the worker moves on to the next job and the job is counted as leaked.
+	// Defaults to one second.
+	LeakTimeout time.Duration
+
+	// PanicHandler is notified when a job task panics, the job failing with a *job.PanicError.
+	// It is also notified of panics of the dead letter queue and of the job done callbacks,
+	// which the worker recovers from to keep running.
+	PanicHandler job.PanicHandler
+
+	// RetainFinished is the number of finished jobs kept for Get and List. Defaults to 1024.
+	RetainFinished int
+
+	// RetainFor is how long finished jobs are kept for Get and List. Zero means no age limit.
+	RetainFor time.Duration
+
+	// Duplicates tells what happens to a job enqueued with the idempotency key of another job.
+	// Defaults to RejectDuplicates.
+	Duplicates DuplicatePolicy
+
+	// IdempotencyWindow is how long a job which succeeded keeps its idempotency key after it finished.
+	// Zero means keys are released when jobs finish. Failed and canceled jobs release their keys,
+	// so the work can be enqueued again.
+	IdempotencyWindow time.Duration
+
+	// EventBuffer is the number of events each subscriber can fall behind before events are dropped.
+	// Defaults to 256.
+	EventBuffer int
+}
+
+// EnqueueOptions allows setup of enqueued jobs.
+type EnqueueOptions struct {
+	Priority Priority
+
+	// At is the time before which the jobs must not start. Zero means as soon as possible.
+	At time.Time
+
+	// Key of the jobs for the rate limiter, like a tenant or a target host. Empty means only the global limit.
+	Key string
+
+	// OnDone is called with the final state of every job after it ran, on the routine of the worker.
+	// It is called too for a job which never ran: a delayed job which was canceled,
+	// and a queued or delayed job dropped by Stop or Shutdown.
+	OnDone func(JobInfo)
 }
 
 // Runner represents a manager of jobs.
 type Runner struct {
 	concurrency int
-	queue       chan *job.Job
+	queue       Queue
+	ready       chan struct{}
+	delayed     *delayed
+	wake        chan struct{}
+	quit        chan struct{}
+	ctx         context.Context
+	cancelCtx   context.CancelCauseFunc
+	clock       clock.Clock
+	limiter     *ratelimit.Limiter
+	dlq         DeadLetterQueue
+	dlqCanceled bool
+	jobTimeout  time.Duration
+	leakTimeout time.Duration
+	leaked      int
+	throttled   int
+	onPanic     job.PanicHandler
+	duplicates  DuplicatePolicy
+	registry    *registry
+	events      *events
 	stop        chan struct{}
 	running     map[uint64]*job.Job
 	toCancel    map[uint64]struct{}
 	wait        chan struct{}
+	drained     chan struct{}
 	state       state
 	lock        sync.RWMutex
 }
@@ -37,6 +142,23 @@ type Runner struct {
 type Status struct {
 	Concurrency int
 	RunningJobs int
+	PendingJobs map[Priority]int
+
+	// DelayedJobs is the number of jobs waiting for their time to be enqueued.
+	DelayedJobs int
+
+	// ThrottledJobs is the number of jobs waiting for the rate limiter. They are counted as running jobs too.
+	ThrottledJobs int
+
+	// LeakedJobs is the number of jobs which exceeded their deadline and ignored the cancellation.
+	// They are still running, but do not occupy a worker.
+	LeakedJobs int
+
+	// DroppedEvents is the number of events subscribers missed because they were too slow.
+	DroppedEvents uint64
+
+	// Draining is true if the runner does not accept new jobs, but is still working.
+	Draining bool
 }
 
 // state of runner
@@ -47,6 +169,8 @@ const (
 	stopped state = iota
 	// running means the runner has routines working on
 	running
+	// draining means the runner has routines working on, but does not accept new jobs
+	draining
 )
 
 // Start starts the runner routines
@@ -54,17 +178,24 @@ func (r *Runner) Start() {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	if r.state == running {
+	if r.state != stopped {
 		return
 	}
 	r.state = running
+	r.ctx, r.cancelCtx = context.WithCancelCause(context.Background())
 
 	for i := 0; i < r.concurrency; i++ {
 		go r.run()
 	}
+
+	r.quit = make(chan struct{})
+	go r.runDelayed(r.quit)
 }
 
-// Stop asks the runner to stop all jobs from running.
+// Stop asks the runner to stop all jobs from running. Jobs are canceled with ErrStopped as cause.
+// Queued jobs and delayed jobs which are still waiting for their time are canceled and dropped,
+// finished as canceled with ErrStopped, and schedules are removed.
+// The dropped jobs are not acknowledged, so a durable queue keeps them.
 func (r *Runner) Stop() {
 	r.lock.Lock()
 	if r.state == stopped {
@@ -72,52 +203,102 @@ func (r *Runner) Stop() {
 		return
 	}
 	r.state = stopped
+
+	// Running jobs are canceled through their context
+	r.cancelCtx(ErrStopped)
+	var dropped []*item
+	for qj, ok := r.queue.Pop(); ok; qj, ok = r.queue.Pop() {
+		dropped = append(dropped, itemOf(qj))
+	}
+	dropped = append(dropped, r.delayed.clear()...)
+	for _, it := range dropped {
+		it.job.Cancel(ErrStopped)
+	}
+
+	close(r.quit)
 	r.lock.Unlock()
 
-	for _, j := range r.running {
-		j.Cancel(errors.New("runner was stopped"))
+	for _, it := range dropped {
+		r.discard(it, StateCanceled, ErrStopped)
 	}
 
+	r.events.emit(Event{Type: EventStopped, Time: r.clock.Now()})
+
 	for i := 0; i < r.concurrency; i++ {
 		r.stop <- struct{}{}
 	}
 }
 
-// Enqueue puts jobs to the runner queue.
+// Enqueue puts jobs to the runner queue with normal priority.
 func (r *Runner) Enqueue(jobs ...*job.Job) error {
+	return r.EnqueueWithOptions(EnqueueOptions{Priority: PriorityNormal}, jobs...)
+}
+
+// EnqueueWithOptions puts jobs to the runner queue with given options.
+// It blocks while the queue is full.
+func (r *Runner) EnqueueWithOptions(opts EnqueueOptions, jobs ...*job.Job) error {
 	r.lock.RLock()
-	isStopped := r.state == stopped
+	err := r.acceptErr()
 	r.lock.RUnlock()
 
-	if isStopped {
-		return errors.New("runner is stopped")
+	if err != nil {
+		return err
 	}
 
 	for i := 0; i < len(jobs); i++ {
-		r.queue <- jobs[i]
+		it := &item{job: jobs[i], priority: opts.Priority, key: opts.Key, onDone: opts.OnDone}
+		if err := r.enqueue(it, opts.At); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// EnqueueAt puts jobs to the runner queue when given time comes.
+// Until then, the jobs do not occupy the queue or a worker routine.
+func (r *Runner) EnqueueAt(t time.Time, jobs ...*job.Job) error {
+	return r.EnqueueWithOptions(EnqueueOptions{Priority: PriorityNormal, At: t}, jobs...)
+}
+
+// EnqueueAfter puts jobs to the runner queue after given duration.
+func (r *Runner) EnqueueAfter(d time.Duration, jobs ...*job.Job) error {
+	return r.EnqueueAt(r.clock.Now().Add(d), jobs...)
+}
+
 // Wait blocks until runner is done with running all the queued jobs.
 func (r *Runner) Wait() {
+	r.WaitContext(context.Background()) // nolint:errcheck
+}
+
+// WaitContext blocks until runner is done with running all the queued jobs or given context is done.
+// It returns the error of the context if it is done first.
+func (r *Runner) WaitContext(ctx context.Context) error {
 	r.lock.RLock()
 	isStopped := r.state == stopped
 	r.lock.RUnlock()
 
 	if isStopped {
-		return
+		return nil
 	}
 
-	<-r.wait
+	select {
+	case <-r.wait:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// Cancel asks a job (by given id) to stop.
+// Cancel asks a job (by given id) to stop. The job is canceled with ErrCanceled as cause.
 func (r *Runner) Cancel(id job.ID) {
 	r.lock.Lock()
-	r.cancel(id)
+	removed := r.cancel(id)
 	r.lock.Unlock()
+
+	if removed != nil {
+		r.discard(removed, StateCanceled, ErrCanceled)
+	}
 }
 
 // ScaleUp increases concurrency by starting new worker routines.
@@ -128,11 +309,10 @@ func (r *Runner) ScaleUp(count int) {
 
 	r.lock.Lock()
 	r.concurrency += count
+	concurrency := r.concurrency
 	r.lock.Unlock()
 
-	for i := 0; i < count; i++ {
-		go r.run()
-	}
+	r.startWorkers(count, concurrency)
 }
 
 // ScaleDown decreases concurrency by asking routines to stop.
@@ -147,8 +327,50 @@ func (r *Runner) ScaleDown(count int) {
 	} else {
 		r.concurrency = 0
 	}
+	concurrency := r.concurrency
 	r.lock.Unlock()
 
+	r.stopWorkers(count, concurrency)
+}
+
+// SetConcurrency scales up or down to given concurrency. The difference is computed under the lock,
+// so concurrent calls do not overshoot. It returns ErrNotRunning if the runner is stopped or draining.
+func (r *Runner) SetConcurrency(concurrency int) error {
+	if concurrency < 0 {
+		return errors.New("concurrency must be zero or positive")
+	}
+
+	r.lock.Lock()
+	if r.state != running {
+		r.lock.Unlock()
+		return ErrNotRunning
+	}
+	diff := concurrency - r.concurrency
+	r.concurrency = concurrency
+	r.lock.Unlock()
+
+	if diff > 0 {
+		r.startWorkers(diff, concurrency)
+	} else if diff < 0 {
+		r.stopWorkers(-diff, concurrency)
+	}
+
+	return nil
+}
+
+// startWorkers starts count worker routines after concurrency was increased to given value.
+func (r *Runner) startWorkers(count, concurrency int) {
+	r.events.emit(Event{Type: EventScaledUp, Time: r.clock.Now(), Concurrency: concurrency})
+
+	for i := 0; i < count; i++ {
+		go r.run()
+	}
+}
+
+// stopWorkers asks count worker routines to stop after concurrency was decreased to given value.
+func (r *Runner) stopWorkers(count, concurrency int) {
+	r.events.emit(Event{Type: EventScaledDown, Time: r.clock.Now(), Concurrency: concurrency})
+
 	for i := 0; i < count; i++ {
 		r.stop <- struct{}{}
 	}
@@ -159,19 +381,146 @@ func (r *Runner) Status() Status {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
 	return Status{
-		Concurrency: r.concurrency,
-		RunningJobs: len(r.running),
+		Concurrency:   r.concurrency,
+		RunningJobs:   len(r.running),
+		PendingJobs:   r.queue.Counts(),
+		DelayedJobs:   r.delayed.len(),
+		ThrottledJobs: r.throttled,
+		LeakedJobs:    r.leaked,
+		DroppedEvents: r.events.dropped.Load(),
+		Draining:      r.state == draining,
 	}
 }
 
-func (r *Runner) run() {
+// enqueue puts a job to the queue, or to the delayed jobs if given time is in the future.
+func (r *Runner) enqueue(it *item, at time.Time) error {
+	r.lock.Lock()
+
+	if err := r.acceptErr(); err != nil {
+		r.lock.Unlock()
+		return err
+	}
+
+	if duplicate, err := r.deduplicate(it); duplicate {
+		r.lock.Unlock()
+		return err
+	}
+
+	now := r.clock.Now()
+	quit := r.quit
+
+	if at.After(now) {
+		r.delayed.push(it, at)
+		r.registry.enqueued(it, at, now)
+		r.emit(EventEnqueued, it.job.ID(), 0, nil)
+		r.lock.Unlock()
+
+		select {
+		case r.wake <- struct{}{}:
+		default:
+		}
+
+		return nil
+	}
+
+	it.enqueued = now
+	if err := r.queue.Push(it.queued()); err != nil {
+		r.lock.Unlock()
+		return err
+	}
+	r.registry.enqueued(it, at, now)
+	r.emit(EventEnqueued, it.job.ID(), 0, nil)
+	r.lock.Unlock()
+
+	select {
+	case r.ready <- struct{}{}:
+	case <-quit:
+	}
+
+	return nil
+}
+
+// runDelayed moves delayed jobs to the queue when they are due.
+func (r *Runner) runDelayed(stop chan struct{}) {
 	for {
+		r.lock.Lock()
+		now := r.clock.Now()
+		due := r.delayed.popDue(now)
+		next, ok := r.delayed.next()
+		pushed := 0
+		var lost []*item
+		var errs []error
+		for _, it := range due {
+			it.enqueued = now
+			if err := r.queue.Push(it.queued()); err != nil {
+				lost = append(lost, it)
+				errs = append(errs, err)
+				continue
+			}
+			r.registry.due(it.job.ID())
+			pushed++
+		}
+		r.lock.Unlock()
+
+		// The jobs are lost, their error is reported only to the callbacks and events
+		for i, it := range lost {
+			r.discard(it, StateFailed, errs[i])
+		}
+
+		for i := 0; i < pushed; i++ {
+			select {
+			case r.ready <- struct{}{}:
+			case <-stop:
+				return
+			}
+		}
+
+		var timer clock.Timer
+		var timeout <-chan time.Time
+		if ok {
+			timer = r.clock.NewTimer(next.Sub(now))
+			timeout = timer.C()
+		}
+
 		select {
-		case j := <-r.queue:
-			hash := hash(j.ID())
+		case <-timeout:
+		case <-r.wake:
+		case <-stop:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
 
+func (r *Runner) run() {
+	for {
+		select {
+		case <-r.ready:
 			r.lock.Lock()
 
+			qj, ok := r.queue.Pop()
+			if !ok {
+				r.lock.Unlock()
+				continue
+			}
+
+			it := itemOf(qj)
+			if qj.item == nil {
+				// Restored by a durable queue
+				r.registry.enqueued(it, time.Time{}, it.enqueued)
+			}
+
+			j := it.job
+			hash := hash(j.ID())
+
 			// Add to running jobs
 			r.running[hash] = j
 
@@ -181,12 +530,14 @@ func (r *Runner) run() {
 				r.cancel(j.ID())
 			}
 
+			ctx := r.ctx
 			r.lock.Unlock()
 
-			j.Run().Wait()
+			r.throttle(ctx, it)
+			r.runJob(ctx, it)
 
 			r.lock.Lock()
-			delete(r.running, hash)
+			r.checkDrained()
 			r.lock.Unlock()
 		case <-r.stop:
 			r.lock.RLock()
@@ -200,18 +551,200 @@ func (r *Runner) run() {
 	}
 }
 
-func (r *Runner) cancel(id job.ID) {
+// throttle waits for the rate limiter until the job can start, the job is canceled or the runner is stopped.
+// A job which was canceled while waiting is still run, with its context canceled, to report it.
+func (r *Runner) throttle(ctx context.Context, it *item) {
+	// Only a job which has to wait is throttled
+	if r.limiter == nil || r.limiter.Allow(it.key) {
+		return
+	}
+
+	r.lock.Lock()
+	r.throttled++
+	r.lock.Unlock()
+
+	defer func() {
+		r.lock.Lock()
+		r.throttled--
+		r.lock.Unlock()
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(it.job.Context(), cancel)
+	defer stop()
+
+	r.limiter.Wait(ctx, it.key) // nolint:errcheck
+}
+
+func (r *Runner) runJob(ctx context.Context, it *item) {
+	if r.jobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.jobTimeout)
+		defer cancel()
+	}
+
+	started := r.clock.Now()
+	r.registry.started(it.job.ID(), started)
+	r.emit(EventStarted, it.job.ID(), started.Sub(it.enqueued), nil)
+	future := it.job.RunContext(ctx)
+	leaked := !r.await(it.job, future)
+
+	r.lock.Lock()
+	delete(r.running, hash(it.job.ID()))
+	if leaked {
+		r.leaked++
+	}
+	r.lock.Unlock()
+
+	if !leaked {
+		r.finish(it, future, started)
+		return
+	}
+
+	go func() {
+		<-future.Done()
+
+		r.lock.Lock()
+		r.leaked--
+		r.lock.Unlock()
+
+		r.finish(it, future, started)
+	}()
+}
+
+// await waits for a job to finish. It returns false if the job exceeded its deadline
+// and did not finish within the leak timeout.
+func (r *Runner) await(j *job.Job, future *job.Future) bool {
+	select {
+	case <-future.Done():
+		return true
+	case <-j.Context().Done():
+	}
+
+	if !errors.Is(j.Cause(), job.ErrDeadlineExceeded) {
+		<-future.Done()
+		return true
+	}
+
+	timer := r.clock.NewTimer(r.leakTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-future.Done():
+		return true
+	case <-timer.C():
+		return false
+	}
+}
+
+// finish is called after a job is done. A panic is recovered, so the worker keeps running.
+func (r *Runner) finish(it *item, future *job.Future, started time.Time) {
+	defer func() {
+		if v := recover(); v != nil {
+			r.panicked(&job.PanicError{Value: v, Stack: debug.Stack()})
+		}
+	}()
+
+	if it.done != nil {
+		defer it.done()
+	}
+
+	var panicErr *job.PanicError
+	if errors.As(future.Error(), &panicErr) {
+		r.panicked(panicErr)
+	}
+
+	finished := r.clock.Now()
+	state, err := finishState(it.job, future)
+	waiters := r.registry.finish(it.job.ID(), state, future.Result(), err, future.Attempts(), finished)
+	r.emit(eventType(state), it.job.ID(), finished.Sub(started), err)
+
+	r.deadLetter(it, state, err, future.Attempts(), started, finished)
+
+	info := JobInfo{
+		ID:             it.job.ID(),
+		State:          state,
+		Priority:       it.priority,
+		IdempotencyKey: it.job.IdempotencyKey(),
+		EnqueuedAt:     it.enqueued,
+		StartedAt:      started,
+		FinishedAt:     finished,
+		Result:         future.Result(),
+		Err:            err,
+		Attempts:       future.Attempts(),
+	}
+	if it.onDone != nil {
+		it.onDone(info)
+	}
+	coalesced(waiters, info)
+
+	// A job interrupted by stopping the runner is not acknowledged, so a durable queue runs it again
+	if state == StateCanceled && errors.Is(err, ErrStopped) {
+		return
+	}
+
+	r.lock.Lock()
+	// An ack error leads to the job being run again after a restart, there is no one to report it to
+	r.queue.Ack(it.queued()) // nolint:errcheck
+	r.lock.Unlock()
+}
+
+func (r *Runner) panicked(err *job.PanicError) {
+	if r.onPanic != nil {
+		r.onPanic(err)
+	}
+}
+
+// cancel cancels a job which is running or delayed, or marks it to be canceled before it runs.
+// A delayed job is removed and returned, to be discarded after the lock is released.
+// It must be called with the lock held.
+func (r *Runner) cancel(id job.ID) *item {
 	hash := hash(id)
 
 	// Cancel now if running
 	j, ok := r.running[hash]
 	if ok {
-		j.Cancel(errors.New("canceled by runner"))
-		return
+		j.Cancel(ErrCanceled)
+		return nil
+	}
+
+	// Remove if waiting for its time
+	if it, ok := r.delayed.remove(id); ok {
+		it.job.Cancel(ErrCanceled)
+		return it
 	}
 
 	// Schedule to be canceled before run
 	r.toCancel[hash] = struct{}{}
+	return nil
+}
+
+// discard finishes a job which never ran: it is recorded in given state, the event is emitted
+// and the callbacks of the job and of the duplicates coalesced into it are called.
+// It must be called without the lock held, as the callbacks can use the runner.
+func (r *Runner) discard(it *item, state State, err error) {
+	if it.done != nil {
+		defer it.done()
+	}
+
+	now := r.clock.Now()
+	waiters := r.registry.finish(it.job.ID(), state, nil, err, 0, now)
+	r.emit(eventType(state), it.job.ID(), 0, err)
+
+	info := JobInfo{
+		ID:             it.job.ID(),
+		State:          state,
+		Priority:       it.priority,
+		IdempotencyKey: it.job.IdempotencyKey(),
+		EnqueuedAt:     it.enqueued,
+		FinishedAt:     now,
+		Err:            err,
+	}
+	if it.onDone != nil {
+		it.onDone(info)
+	}
+	coalesced(waiters, info)
 }
 
 // New creates a new job runner.
@@ -222,10 +755,49 @@ func New(c Config) *Runner {
 	if c.QueueSize == 0 {
 		c.QueueSize = queueSize
 	}
+	if c.Clock == nil {
+		c.Clock = clock.New()
+	}
+	if c.LeakTimeout == 0 {
+		c.LeakTimeout = leakTimeout
+	}
+	if c.RetainFinished == 0 {
+		c.RetainFinished = retainFinished
+	}
+	if c.EventBuffer == 0 {
+		c.EventBuffer = eventBuffer
+	}
+	if c.Queue == nil {
+		c.Queue = newQueue(c.Aging, c.Clock.Now())
+	}
+
+	// Every pending job has a ready token, including the ones restored by a durable queue
+	pending := c.Queue.Len()
+	if pending > c.QueueSize {
+		c.QueueSize = pending
+	}
+	ready := make(chan struct{}, c.QueueSize)
+	for i := 0; i < pending; i++ {
+		ready <- struct{}{}
+	}
 
 	return &Runner{
 		concurrency: c.Concurrency,
-		queue:       make(chan *job.Job, c.QueueSize),
+		queue:       c.Queue,
+		ready:       ready,
+		delayed:     &delayed{},
+		wake:        make(chan struct{}, 1),
+		quit:        make(chan struct{}),
+		clock:       c.Clock,
+		limiter:     c.RateLimiter,
+		dlq:         c.DeadLetters,
+		dlqCanceled: c.DeadLetterCanceled,
+		jobTimeout:  c.JobTimeout,
+		leakTimeout: c.LeakTimeout,
+		onPanic:     c.PanicHandler,
+		duplicates:  c.Duplicates,
+		registry:    newRegistry(c.RetainFinished, c.RetainFor, c.IdempotencyWindow),
+		events:      newEvents(c.EventBuffer),
 		stop:        make(chan struct{}, c.QueueSize),
 		running:     make(map[uint64]*job.Job),
 		toCancel:    make(map[uint64]struct{}),
@@ -234,6 +806,18 @@ func New(c Config) *Runner {
 	}
 }
 
+// acceptErr returns why new jobs are not accepted, or nil if they are.
+func (r *Runner) acceptErr() error {
+	switch r.state {
+	case stopped:
+		return errors.New("runner is stopped")
+	case draining:
+		return errors.New("runner is draining")
+	default:
+		return nil
+	}
+}
+
 func hash(id job.ID) uint64 {
 	return xxhash.Sum64String(string(id))
 }
@@ -1,9 +1,13 @@
 package runner_test
 
 import (
+	"context"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/andreiavrammsd/workexec/clock"
 	"github.com/andreiavrammsd/workexec/job"
 	"github.com/andreiavrammsd/workexec/runner"
 )
@@ -54,6 +58,355 @@ func TestRunner_StopWithCancelRunningJobs(t *testing.T) {
 	r.Wait()
 }
 
+func TestRunner_StopCancelsContext(t *testing.T) {
+	r := runner.New(runner.Config{})
+	r.Start()
+
+	task := &contextTask{started: make(chan struct{})}
+	testJob, err := job.NewContext(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Enqueue(testJob); err != nil {
+		t.Fatal(err)
+	}
+
+	<-task.started
+	r.Stop()
+	r.Wait()
+
+	for r.Status().RunningJobs > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if !errors.Is(testJob.Cause(), runner.ErrStopped) {
+		t.Errorf("got %v, expected runner stopped cause", testJob.Cause())
+	}
+}
+
+func TestRunner_CancelContext(t *testing.T) {
+	r := runner.New(runner.Config{})
+	r.Start()
+	defer r.Stop()
+
+	task := &contextTask{started: make(chan struct{})}
+	testJob, err := job.NewContext(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Enqueue(testJob); err != nil {
+		t.Fatal(err)
+	}
+
+	<-task.started
+	r.Cancel(testJob.ID())
+
+	<-testJob.Context().Done()
+	if !errors.Is(testJob.Cause(), runner.ErrCanceled) {
+		t.Errorf("got %v, expected runner canceled cause", testJob.Cause())
+	}
+}
+
+type contextTask struct {
+	started chan struct{}
+}
+
+func (t *contextTask) RunContext(ctx context.Context) (interface{}, error) {
+	close(t.started)
+	<-ctx.Done()
+	return nil, context.Cause(ctx)
+}
+
+func TestRunner_EnqueueWithPriority(t *testing.T) {
+	r := runner.New(runner.Config{
+		Concurrency: 1,
+		QueueSize:   4,
+	})
+	r.Start()
+	defer r.Stop()
+
+	release := make(chan struct{})
+	blocking, err := job.New(&blockingTask{release: release})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Enqueue(blocking); err != nil {
+		t.Fatal(err)
+	}
+
+	for r.Status().RunningJobs == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	order := &orderedTasks{}
+	enqueue := func(name string, priority runner.Priority) {
+		j, err := job.New(&orderedTask{name: name, order: order})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.EnqueueWithOptions(runner.EnqueueOptions{Priority: priority}, j); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enqueue("low", runner.PriorityLow)
+	enqueue("normal", runner.PriorityNormal)
+	enqueue("high", runner.PriorityHigh)
+
+	status := r.Status()
+	if status.PendingJobs[runner.PriorityLow] != 1 || status.PendingJobs[runner.PriorityHigh] != 1 {
+		t.Errorf("unexpected pending jobs: %v", status.PendingJobs)
+	}
+
+	close(release)
+
+	for len(order.get()) < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	expected := []string{"high", "normal", "low"}
+	actual := order.get()
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("got %v, expected %v", actual, expected)
+		}
+	}
+}
+
+func TestRunner_SetConcurrency(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+
+	if err := r.SetConcurrency(4); !errors.Is(err, runner.ErrNotRunning) {
+		t.Errorf("got %v, expected runner not to be running", err)
+	}
+
+	r.Start()
+	defer r.Stop()
+
+	// Concurrent calls to the same concurrency do not add up
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.SetConcurrency(4); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if c := r.Status().Concurrency; c != 4 {
+		t.Errorf("got concurrency %d, expected 4", c)
+	}
+
+	if err := r.SetConcurrency(2); err != nil {
+		t.Fatal(err)
+	}
+	if c := r.Status().Concurrency; c != 2 {
+		t.Errorf("got concurrency %d, expected 2", c)
+	}
+
+	if err := r.SetConcurrency(-1); err == nil {
+		t.Error("expected error for negative concurrency")
+	}
+}
+
+func TestRunner_EnqueueAfter(t *testing.T) {
+	r := runner.New(runner.Config{
+		Concurrency: 1,
+		QueueSize:   1,
+	})
+	r.Start()
+	defer r.Stop()
+
+	order := &orderedTasks{}
+	delayedJob, err := job.New(&orderedTask{name: "delayed", order: order})
+	if err != nil {
+		t.Fatal(err)
+	}
+	immediateJob, err := job.New(&orderedTask{name: "immediate", order: order})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.EnqueueAfter(time.Millisecond*20, delayedJob); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Enqueue(immediateJob); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Status().DelayedJobs != 1 {
+		t.Error("expected one delayed job")
+	}
+
+	for len(order.get()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if actual := order.get(); actual[0] != "immediate" || actual[1] != "delayed" {
+		t.Errorf("got %v, expected delayed job to run last", actual)
+	}
+
+	if r.Status().DelayedJobs != 0 {
+		t.Error("expected no delayed jobs")
+	}
+}
+
+func TestRunner_EnqueueAfterWithClock(t *testing.T) {
+	c := clock.NewFake(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := runner.New(runner.Config{Clock: c})
+	r.Start()
+	defer r.Stop()
+
+	order := &orderedTasks{}
+	delayedJob, err := job.New(&orderedTask{name: "delayed", order: order})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.EnqueueAfter(time.Minute, delayedJob); err != nil {
+		t.Fatal(err)
+	}
+
+	// The delay is measured on the clock of the runner
+	waitTimers(c, 1)
+	c.Advance(time.Second * 59)
+	waitTimers(c, 1)
+	if len(order.get()) != 0 {
+		t.Error("expected delayed job not to run before its delay")
+	}
+
+	c.Advance(time.Second)
+	for len(order.get()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunner_CancelDelayedJob(t *testing.T) {
+	r := runner.New(runner.Config{})
+	r.Start()
+	defer r.Stop()
+
+	delayedJob, err := job.New(&cancelableTask{myTask: &myTask{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.EnqueueAt(time.Now().Add(time.Hour), delayedJob); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Cancel(delayedJob.ID())
+
+	if r.Status().DelayedJobs != 0 {
+		t.Error("expected delayed job to be removed")
+	}
+	if !delayedJob.IsCanceled() {
+		t.Error("expected delayed job to be canceled")
+	}
+}
+
+func TestRunner_OnDoneOfJobsWhichNeverRan(t *testing.T) {
+	r := runner.New(runner.Config{})
+	r.Start()
+
+	done := make(chan runner.JobInfo, 2)
+	opts := runner.EnqueueOptions{
+		At: time.Now().Add(time.Hour),
+		OnDone: func(info runner.JobInfo) {
+			done <- info
+		},
+	}
+
+	canceledJob, err := job.New(&task{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stoppedJob, err := job.New(&task{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.EnqueueWithOptions(opts, canceledJob, stoppedJob); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Cancel(canceledJob.ID())
+	if info := <-done; info.ID != canceledJob.ID() || info.State != runner.StateCanceled ||
+		!errors.Is(info.Err, runner.ErrCanceled) {
+		t.Errorf("got %+v, expected canceled job", info)
+	}
+
+	r.Stop()
+	if info := <-done; info.ID != stoppedJob.ID() || info.State != runner.StateCanceled ||
+		!errors.Is(info.Err, runner.ErrStopped) {
+		t.Errorf("got %+v, expected stopped job", info)
+	}
+}
+
+func TestRunner_StopWithDelayedJobs(t *testing.T) {
+	r := runner.New(runner.Config{})
+	r.Start()
+
+	delayedJob, err := job.New(&cancelableTask{myTask: &myTask{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.EnqueueAfter(time.Hour, delayedJob); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Stop()
+	r.Wait()
+
+	if r.Status().DelayedJobs != 0 {
+		t.Error("expected delayed jobs to be dropped")
+	}
+	if !delayedJob.IsCanceled() {
+		t.Error("expected delayed job to be canceled")
+	}
+}
+
+type blockingTask struct {
+	release chan struct{}
+}
+
+func (t *blockingTask) Run(*job.Job) (interface{}, error) {
+	<-t.release
+	return nil, nil
+}
+
+type orderedTasks struct {
+	names []string
+	lock  sync.Mutex
+}
+
+func (o *orderedTasks) add(name string) {
+	o.lock.Lock()
+	o.names = append(o.names, name)
+	o.lock.Unlock()
+}
+
+func (o *orderedTasks) get() []string {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return append([]string(nil), o.names...)
+}
+
+type orderedTask struct {
+	name  string
+	order *orderedTasks
+}
+
+func (t *orderedTask) Run(*job.Job) (interface{}, error) {
+	t.order.add(t.name)
+	return nil, nil
+}
+
 type task struct {
 	duration time.Duration
 }
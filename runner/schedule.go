@@ -0,0 +1,200 @@
+package runner
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/cron"
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+// OverlapPolicy decides what happens when a schedule is due while its previous job did not finish.
+type OverlapPolicy int
+
+const (
+	// OverlapAllow runs jobs of the same schedule concurrently.
+	OverlapAllow OverlapPolicy = iota
+	// OverlapSkip drops the activation if the previous job did not finish.
+	OverlapSkip
+	// OverlapQueue runs the job of the activation after the previous job finished.
+	OverlapQueue
+)
+
+// ScheduleOptions allows setup of scheduled jobs.
+type ScheduleOptions struct {
+	Priority Priority
+	Overlap  OverlapPolicy
+	// Key of the jobs for the rate limiter
+	Key string
+}
+
+// JobFactory creates a new job for every activation of a schedule.
+type JobFactory func() (*job.Job, error)
+
+// Schedule enqueues jobs at the times given by a cron expression.
+type Schedule struct {
+	runner   *Runner
+	schedule cron.Schedule
+	factory  JobFactory
+	opts     ScheduleOptions
+	active   int
+	backlog  int
+	paused   bool
+	removed  chan struct{}
+	remove   sync.Once
+	lock     sync.Mutex
+}
+
+// Pause stops enqueuing jobs until Resume is called. Activations while paused are skipped.
+func (s *Schedule) Pause() {
+	s.lock.Lock()
+	s.paused = true
+	s.lock.Unlock()
+}
+
+// Resume continues enqueuing jobs after Pause.
+func (s *Schedule) Resume() {
+	s.lock.Lock()
+	s.paused = false
+	s.lock.Unlock()
+}
+
+// IsPaused returns true if schedule is paused.
+func (s *Schedule) IsPaused() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.paused
+}
+
+// Remove stops the schedule. Jobs already enqueued are not affected.
+func (s *Schedule) Remove() {
+	s.remove.Do(func() {
+		s.lock.Lock()
+		s.backlog = 0
+		s.lock.Unlock()
+
+		close(s.removed)
+	})
+}
+
+func (s *Schedule) run(quit chan struct{}) {
+	for {
+		now := s.runner.clock.Now()
+		next := s.schedule.Next(now)
+		if next.IsZero() {
+			return
+		}
+
+		timer := s.runner.clock.NewTimer(next.Sub(now))
+
+		select {
+		case <-timer.C():
+			s.activate()
+		case <-s.removed:
+			timer.Stop()
+			return
+		case <-quit:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (s *Schedule) activate() {
+	s.lock.Lock()
+
+	if s.paused {
+		s.lock.Unlock()
+		return
+	}
+
+	if s.active > 0 {
+		switch s.opts.Overlap {
+		case OverlapSkip:
+			s.lock.Unlock()
+			return
+		case OverlapQueue:
+			s.backlog++
+			s.lock.Unlock()
+			return
+		case OverlapAllow:
+		}
+	}
+
+	s.active++
+	s.lock.Unlock()
+
+	s.enqueue()
+}
+
+func (s *Schedule) enqueue() {
+	j, err := s.factory()
+	if err != nil || j == nil {
+		s.done()
+		return
+	}
+
+	it := &item{job: j, priority: s.opts.Priority, key: s.opts.Key, done: s.done}
+	if err := s.runner.enqueue(it, time.Time{}); err != nil {
+		s.done()
+	}
+}
+
+// done is called after a job of the schedule was run.
+func (s *Schedule) done() {
+	s.lock.Lock()
+	s.active--
+
+	next := s.backlog > 0
+	if next {
+		s.backlog--
+		s.active++
+	}
+	s.lock.Unlock()
+
+	// Not blocking the routine which called done, as it could be a worker needed to free the queue.
+	if next {
+		go s.enqueue()
+	}
+}
+
+// Schedule creates a job with given factory and enqueues it at every activation of the cron expression.
+// Jobs of the same schedule are allowed to run concurrently.
+func (r *Runner) Schedule(spec string, factory JobFactory) (*Schedule, error) {
+	return r.ScheduleWithOptions(spec, ScheduleOptions{Priority: PriorityNormal, Overlap: OverlapAllow}, factory)
+}
+
+// ScheduleWithOptions creates a job with given factory and enqueues it at every activation of the
+// cron expression, with given options. See the cron package for supported expressions.
+func (r *Runner) ScheduleWithOptions(spec string, opts ScheduleOptions, factory JobFactory) (*Schedule, error) {
+	if factory == nil {
+		return nil, errors.New("nil job factory")
+	}
+
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schedule{
+		runner:   r,
+		schedule: schedule,
+		factory:  factory,
+		opts:     opts,
+		removed:  make(chan struct{}),
+	}
+
+	r.lock.RLock()
+	err = r.acceptErr()
+	quit := r.quit
+	r.lock.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	go s.run(quit)
+
+	return s, nil
+}
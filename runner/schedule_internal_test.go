@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule_DoneAfterShutdown(t *testing.T) {
+	r := New(Config{Concurrency: 1})
+	r.Start()
+
+	// The worker is busy, so the job of the schedule stays queued
+	release := make(chan struct{})
+	started := make(chan struct{})
+	blocking, err := job.New(&waitTask{started: started, release: release})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Enqueue(blocking))
+	<-started
+
+	opts := ScheduleOptions{Priority: PriorityNormal, Overlap: OverlapSkip}
+	s, err := r.ScheduleWithOptions("@yearly", opts, func() (*job.Job, error) {
+		return newTestJob(t), nil
+	})
+	assert.NoError(t, err)
+
+	s.activate()
+	assert.Equal(t, 1, r.Status().PendingJobs[PriorityNormal])
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	neverRan, err := r.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, neverRan, 1)
+	close(release)
+
+	// The dropped job is not running anymore for the overlap policy
+	s.lock.Lock()
+	assert.Equal(t, 0, s.active)
+	s.lock.Unlock()
+}
+
+type waitTask struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (t *waitTask) Run(*job.Job) (interface{}, error) {
+	close(t.started)
+	<-t.release
+	return nil, nil
+}
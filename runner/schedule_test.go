@@ -0,0 +1,158 @@
+package runner_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_ScheduleErrors(t *testing.T) {
+	r := runner.New(runner.Config{})
+
+	_, err := r.Schedule("@every 1s", nil)
+	assert.Error(t, err)
+
+	factory := func() (*job.Job, error) {
+		return job.New(&task{})
+	}
+
+	_, err = r.Schedule("* * *", factory)
+	assert.Error(t, err)
+
+	_, err = r.Schedule("@every 1s", factory)
+	assert.Error(t, err, "expected runner is stopped error")
+}
+
+func TestRunner_Schedule(t *testing.T) {
+	c := clock.NewFake(time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC))
+	r := runner.New(runner.Config{Clock: c})
+	r.Start()
+	defer r.Stop()
+
+	var runs int32
+	s, err := r.Schedule("*/5 * * * *", func() (*job.Job, error) {
+		return job.New(&countTask{count: &runs})
+	})
+	assert.NoError(t, err)
+
+	waitTimers(c, 1)
+
+	c.Advance(time.Minute * 4)
+	waitTimers(c, 1)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&runs))
+
+	c.Advance(time.Minute)
+	waitTimers(c, 1)
+	waitRuns(&runs, 1)
+
+	s.Pause()
+	assert.True(t, s.IsPaused())
+	c.Advance(time.Minute * 5)
+	waitTimers(c, 1)
+
+	s.Resume()
+	assert.False(t, s.IsPaused())
+	c.Advance(time.Minute * 5)
+	waitTimers(c, 1)
+	waitRuns(&runs, 2)
+
+	s.Remove()
+	s.Remove()
+	waitTimers(c, 0)
+
+	c.Advance(time.Minute * 5)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
+
+func TestRunner_ScheduleWithFactoryError(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	r := runner.New(runner.Config{Clock: c})
+	r.Start()
+	defer r.Stop()
+
+	var calls int32
+	_, err := r.Schedule("@every 1s", func() (*job.Job, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("factory error")
+	})
+	assert.NoError(t, err)
+
+	waitTimers(c, 1)
+	c.Advance(time.Second)
+	waitTimers(c, 1)
+	c.Advance(time.Second)
+	waitTimers(c, 1)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, 0, r.Status().RunningJobs)
+}
+
+func TestRunner_ScheduleOverlap(t *testing.T) {
+	tests := []struct {
+		overlap  runner.OverlapPolicy
+		expected int32
+	}{
+		{overlap: runner.OverlapSkip, expected: 1},
+		{overlap: runner.OverlapQueue, expected: 3},
+		{overlap: runner.OverlapAllow, expected: 3},
+	}
+
+	for _, test := range tests {
+		c := clock.NewFake(time.Now())
+		r := runner.New(runner.Config{Clock: c})
+		r.Start()
+
+		var runs int32
+		release := make(chan struct{})
+		opts := runner.ScheduleOptions{Overlap: test.overlap}
+		_, err := r.ScheduleWithOptions("@every 1s", opts, func() (*job.Job, error) {
+			return job.New(&countTask{count: &runs, release: release})
+		})
+		assert.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			waitTimers(c, 1)
+			c.Advance(time.Second)
+		}
+		waitTimers(c, 1)
+
+		close(release)
+		waitRuns(&runs, test.expected)
+
+		time.Sleep(time.Millisecond * 10)
+		assert.Equal(t, test.expected, atomic.LoadInt32(&runs), test.overlap)
+
+		r.Stop()
+	}
+}
+
+type countTask struct {
+	count   *int32
+	release chan struct{}
+}
+
+func (t *countTask) Run(*job.Job) (interface{}, error) {
+	if t.release != nil {
+		<-t.release
+	}
+	atomic.AddInt32(t.count, 1)
+	return nil, nil
+}
+
+func waitTimers(c *clock.Fake, n int) {
+	for c.Timers() != n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitRuns(runs *int32, n int32) {
+	for atomic.LoadInt32(runs) < n {
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"context"
+	"errors"
+
+	"github.com/andreiavrammsd/workexec/job"
+)
+
+// Drain stops accepting new jobs, while queued, delayed and running jobs go on.
+// A draining runner can only be stopped.
+func (r *Runner) Drain() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.state != running {
+		return
+	}
+
+	r.state = draining
+	r.drained = make(chan struct{})
+	r.checkDrained()
+}
+
+// Shutdown stops accepting new jobs and waits for the queued and running jobs to finish, then stops the runner.
+// Delayed jobs are not waited for. If the context is done first, the queued jobs are dropped and the runner is
+// stopped, canceling the running jobs, and the error of the context is returned.
+// The jobs which never ran (delayed and dropped) are returned in the order they would have run,
+// delayed jobs last. They are not canceled, so they can be persisted and enqueued again.
+// The dropped jobs are not acknowledged, so a durable queue keeps them.
+// The jobs which never ran are finished as canceled with ErrStopped: their OnDone callbacks are called
+// and the schedules which created them do not count them as running anymore.
+func (r *Runner) Shutdown(ctx context.Context) ([]*job.Job, error) {
+	r.Drain()
+
+	r.lock.Lock()
+	if r.state != draining {
+		r.lock.Unlock()
+		return nil, errors.New("runner is stopped")
+	}
+
+	delayed := r.delayed.clear()
+	drained := r.drained
+	r.lock.Unlock()
+
+	var err error
+	var dropped []*item
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+
+		r.lock.Lock()
+		for qj, ok := r.queue.Pop(); ok; qj, ok = r.queue.Pop() {
+			dropped = append(dropped, itemOf(qj))
+		}
+		r.lock.Unlock()
+	}
+
+	dropped = append(dropped, delayed...)
+	neverRan := make([]*job.Job, len(dropped))
+	for i, it := range dropped {
+		r.discard(it, StateCanceled, ErrStopped)
+		neverRan[i] = it.job
+	}
+
+	r.Stop()
+
+	return neverRan, err
+}
+
+// checkDrained signals a draining runner has no queued or running jobs. It must be called with the lock held.
+func (r *Runner) checkDrained() {
+	if r.state != draining || r.queue.Len() > 0 || len(r.running) > 0 {
+		return
+	}
+
+	select {
+	case <-r.drained:
+	default:
+		close(r.drained)
+	}
+}
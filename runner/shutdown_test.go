@@ -0,0 +1,127 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Drain(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+	defer r.Stop()
+
+	blocking := &blockingTask{release: make(chan struct{})}
+	running, err := job.New(blocking)
+	assert.NoError(t, err)
+	queued, err := job.New(&task{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Enqueue(running, queued))
+
+	r.Drain()
+	assert.True(t, r.Status().Draining)
+
+	next, err := job.New(&task{})
+	assert.NoError(t, err)
+	assert.EqualError(t, r.Enqueue(next), "runner is draining")
+
+	_, err = r.Schedule("@every 1s", func() (*job.Job, error) { return job.New(&task{}) })
+	assert.EqualError(t, err, "runner is draining")
+
+	// Jobs given before draining are still run
+	close(blocking.release)
+	waitState(t, r, queued.ID(), runner.StateSucceeded)
+
+	// A draining runner is not started again
+	r.Start()
+	assert.True(t, r.Status().Draining)
+}
+
+func TestRunner_Shutdown(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+
+	blocking := &blockingTask{release: make(chan struct{})}
+	running, err := job.New(blocking)
+	assert.NoError(t, err)
+	queued, err := job.New(&task{})
+	assert.NoError(t, err)
+	delayed, err := job.New(&task{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Enqueue(running, queued))
+	assert.NoError(t, r.EnqueueAfter(time.Hour, delayed))
+	waitState(t, r, running.ID(), runner.StateRunning)
+
+	time.AfterFunc(time.Millisecond*10, func() {
+		close(blocking.release)
+	})
+
+	neverRan, err := r.Shutdown(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []*job.Job{delayed}, neverRan)
+	assert.False(t, delayed.IsCanceled())
+
+	waitState(t, r, running.ID(), runner.StateSucceeded)
+	waitState(t, r, queued.ID(), runner.StateSucceeded)
+	waitState(t, r, delayed.ID(), runner.StateCanceled)
+
+	assert.EqualError(t, r.Enqueue(queued), "runner is stopped")
+
+	_, err = r.Shutdown(context.Background())
+	assert.EqualError(t, err, "runner is stopped")
+}
+
+func TestRunner_ShutdownDeadline(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+
+	task := &contextTask{started: make(chan struct{})}
+	running, err := job.NewContext(task)
+	assert.NoError(t, err)
+
+	queued := make([]*job.Job, 2)
+	for i := range queued {
+		queued[i], err = job.New(&cancelableTask{myTask: &myTask{}})
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, r.Enqueue(running))
+	<-task.started
+	assert.NoError(t, r.Enqueue(queued...))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	neverRan, err := r.Shutdown(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, queued, neverRan)
+
+	info := waitState(t, r, running.ID(), runner.StateCanceled)
+	assert.Equal(t, runner.ErrStopped, info.Err)
+
+	for _, j := range queued {
+		assert.False(t, j.IsCanceled())
+		info := waitState(t, r, j.ID(), runner.StateCanceled)
+		assert.True(t, info.StartedAt.IsZero())
+	}
+}
+
+func TestRunner_WaitContext(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	assert.NoError(t, r.WaitContext(context.Background()), "not started")
+
+	r.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, r.WaitContext(ctx))
+
+	r.Stop()
+	assert.NoError(t, r.WaitContext(context.Background()))
+}
@@ -0,0 +1,68 @@
+package runner_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_JobTimeout(t *testing.T) {
+	r := runner.New(runner.Config{
+		Concurrency: 1,
+		DeadLetters: runner.NewMemoryDeadLetterQueue(0),
+		JobTimeout:  time.Millisecond * 10,
+	})
+	r.Start()
+	defer r.Stop()
+
+	testJob, err := job.NewContext(&contextTask{started: make(chan struct{})})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Enqueue(testJob))
+
+	for len(r.DeadLetters()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	letter, ok := r.DeadLetter(testJob.ID())
+	assert.True(t, ok)
+	assert.Equal(t, job.ErrDeadlineExceeded, letter.Err)
+	assert.Equal(t, 0, r.Status().LeakedJobs)
+}
+
+func TestRunner_LeakedJob(t *testing.T) {
+	r := runner.New(runner.Config{
+		Concurrency: 1,
+		LeakTimeout: time.Millisecond * 10,
+	})
+	r.Start()
+	defer r.Stop()
+
+	release := make(chan struct{})
+	leaking, err := job.New(&blockingTask{release: release}, job.WithTimeout(time.Millisecond*10))
+	assert.NoError(t, err)
+
+	order := &orderedTasks{}
+	next, err := job.New(&orderedTask{name: "next", order: order})
+	assert.NoError(t, err)
+
+	assert.NoError(t, r.Enqueue(leaking, next))
+
+	// The worker is reclaimed and runs the next job, which is not running anymore when its result is recorded
+	for len(order.get()) == 0 || r.Status().RunningJobs > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, []string{"next"}, order.get())
+
+	status := r.Status()
+	assert.Equal(t, 1, status.LeakedJobs)
+	assert.Equal(t, 0, status.RunningJobs)
+
+	close(release)
+
+	for r.Status().LeakedJobs > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
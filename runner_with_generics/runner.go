@@ -0,0 +1,142 @@
+// Package runner_with_generics runs typed jobs of job_with_generics on a runner, with the same queue,
+// priorities, cancellation by ID, scaling and status. Enqueue returns the typed futures of the jobs,
+// so results are retrieved without type assertions.
+//
+// Typed jobs have no idempotency key, retry policy, timeout or deadline options, so they are never deduplicated
+// or retried, and Config.JobTimeout is the only limit of their run.
+package runner_with_generics
+
+import (
+	"context"
+	"errors"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/job_with_generics"
+	"github.com/andreiavrammsd/workexec/runner"
+)
+
+// Runner runs jobs with results of type T.
+type Runner[T any] struct {
+	runner *runner.Runner
+}
+
+// Start starts the runner routines.
+func (r *Runner[T]) Start() {
+	r.runner.Start()
+}
+
+// Stop asks the runner to stop all jobs from running. The futures of the queued and delayed jobs
+// are canceled with runner.ErrStopped.
+func (r *Runner[T]) Stop() {
+	r.runner.Stop()
+}
+
+// Wait blocks until runner is done with running all the queued jobs.
+func (r *Runner[T]) Wait() {
+	r.runner.Wait()
+}
+
+// Enqueue puts jobs to the runner queue with normal priority and returns their futures, in order.
+func (r *Runner[T]) Enqueue(jobs ...*job_with_generics.Job[T]) ([]*job_with_generics.Future[T], error) {
+	return r.EnqueueWithOptions(runner.EnqueueOptions{Priority: runner.PriorityNormal}, jobs...)
+}
+
+// EnqueueWithOptions puts jobs to the runner queue with given options and returns their futures, in order.
+// If a job cannot be enqueued, the futures of the jobs enqueued before it are returned with the error.
+// The future of a job which never runs, like a delayed job which is canceled or a job dropped by Stop
+// or Shutdown, is canceled with the error given to the OnDone callback.
+func (r *Runner[T]) EnqueueWithOptions(
+	opts runner.EnqueueOptions, jobs ...*job_with_generics.Job[T],
+) ([]*job_with_generics.Future[T], error) {
+	futures := make([]*job_with_generics.Future[T], 0, len(jobs))
+
+	for _, j := range jobs {
+		if j == nil {
+			return futures, errors.New("nil job passed to runner")
+		}
+
+		adapted, err := job.NewContext(&task[T]{job: j}, job.WithID(job.ID(j.ID())))
+		if err != nil {
+			return futures, err
+		}
+
+		future := j.Future()
+		if err := r.runner.EnqueueWithOptions(discarding(opts, j), adapted); err != nil {
+			return futures, err
+		}
+
+		futures = append(futures, future)
+	}
+
+	return futures, nil
+}
+
+// Cancel asks a job (by given id) to stop. Only jobs with a CancelableTask can be stopped while running.
+func (r *Runner[T]) Cancel(id job_with_generics.ID) {
+	r.runner.Cancel(job.ID(id))
+}
+
+// ScaleUp increases concurrency by starting new worker routines.
+func (r *Runner[T]) ScaleUp(count int) {
+	r.runner.ScaleUp(count)
+}
+
+// ScaleDown decreases concurrency by asking routines to stop.
+func (r *Runner[T]) ScaleDown(count int) {
+	r.runner.ScaleDown(count)
+}
+
+// Status returns runner state.
+func (r *Runner[T]) Status() runner.Status {
+	return r.runner.Status()
+}
+
+// Get returns the state of a job by its id.
+func (r *Runner[T]) Get(id job_with_generics.ID) (runner.JobInfo, bool) {
+	return r.runner.Get(job.ID(id))
+}
+
+// Runner returns the untyped runner the jobs are run on.
+func (r *Runner[T]) Runner() *runner.Runner {
+	return r.runner
+}
+
+// New creates a runner of typed jobs.
+func New[T any](c runner.Config) *Runner[T] {
+	return Wrap[T](runner.New(c))
+}
+
+// Wrap runs typed jobs on an existing runner, which can run jobs of other types too.
+func Wrap[T any](r *runner.Runner) *Runner[T] {
+	return &Runner[T]{runner: r}
+}
+
+// discarding returns the options with an OnDone callback which settles the future of a job which never ran.
+// A job which ran took its future already, so it is not affected.
+func discarding[T any](opts runner.EnqueueOptions, j *job_with_generics.Job[T]) runner.EnqueueOptions {
+	onDone := opts.OnDone
+	opts.OnDone = func(info runner.JobInfo) {
+		j.Discard(info.Err)
+		if onDone != nil {
+			onDone(info)
+		}
+	}
+
+	return opts
+}
+
+// task runs a typed job as the task of an untyped job.
+type task[T any] struct {
+	job *job_with_generics.Job[T]
+}
+
+// RunContext runs the typed job and cancels it when the context is done.
+func (t *task[T]) RunContext(ctx context.Context) (interface{}, error) {
+	stop := context.AfterFunc(ctx, func() {
+		t.job.Cancel(context.Cause(ctx))
+	})
+	defer stop()
+
+	future := t.job.Run()
+	return future.Result(), future.Error()
+}
@@ -0,0 +1,220 @@
+package runner_with_generics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job_with_generics"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/andreiavrammsd/workexec/runner_with_generics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_Enqueue(t *testing.T) {
+	r := runner_with_generics.New[int](runner.Config{Concurrency: 2})
+	r.Start()
+	defer r.Stop()
+
+	double, err := job_with_generics.New[int](&doubleTask{n: 2})
+	assert.NoError(t, err)
+	failing, err := job_with_generics.New[int](&doubleTask{n: -1})
+	assert.NoError(t, err)
+
+	futures, err := r.Enqueue(double, failing)
+	assert.NoError(t, err)
+	assert.Len(t, futures, 2)
+
+	assert.Equal(t, 4, futures[0].Result())
+	assert.NoError(t, futures[0].Error())
+
+	futures[1].Wait()
+	assert.EqualError(t, futures[1].Error(), "negative number")
+
+	info := waitState(t, r, double.ID(), runner.StateSucceeded)
+	assert.Equal(t, 4, info.Result)
+
+	futures, err = r.Enqueue(nil)
+	assert.Empty(t, futures)
+	assert.Error(t, err)
+}
+
+func TestRunner_Cancel(t *testing.T) {
+	r := runner_with_generics.New[int](runner.Config{Concurrency: 1})
+	r.Start()
+	defer r.Stop()
+
+	blocking := &blockingTask{started: make(chan struct{})}
+	j, err := job_with_generics.New[int](blocking)
+	assert.NoError(t, err)
+
+	futures, err := r.Enqueue(j)
+	assert.NoError(t, err)
+
+	<-blocking.started
+	r.Cancel(j.ID())
+
+	futures[0].Wait()
+	assert.True(t, futures[0].IsCanceled())
+	assert.ErrorIs(t, blocking.canceledWith, runner.ErrCanceled)
+
+	waitState(t, r, j.ID(), runner.StateCanceled)
+}
+
+func TestRunner_FuturesOfJobsWhichNeverRan(t *testing.T) {
+	r := runner_with_generics.New[int](runner.Config{Concurrency: 1})
+	r.Start()
+
+	blocking := &blockingTask{started: make(chan struct{})}
+	running, err := job_with_generics.New[int](blocking)
+	assert.NoError(t, err)
+	_, err = r.Enqueue(running)
+	assert.NoError(t, err)
+	<-blocking.started
+
+	done := make(chan runner.JobInfo, 3)
+	opts := runner.EnqueueOptions{
+		At: time.Now().Add(time.Hour),
+		OnDone: func(info runner.JobInfo) {
+			done <- info
+		},
+	}
+
+	canceled, err := job_with_generics.New[int](&doubleTask{n: 1})
+	assert.NoError(t, err)
+	delayed, err := job_with_generics.New[int](&doubleTask{n: 1})
+	assert.NoError(t, err)
+	futures, err := r.EnqueueWithOptions(opts, canceled, delayed)
+	assert.NoError(t, err)
+
+	r.Cancel(canceled.ID())
+	futures[0].Wait()
+	assert.True(t, futures[0].IsCanceled())
+	assert.ErrorIs(t, futures[0].Error(), runner.ErrCanceled)
+	assert.Equal(t, canceled.ID(), job_with_generics.ID((<-done).ID))
+
+	queued, err := job_with_generics.New[int](&doubleTask{n: 1})
+	assert.NoError(t, err)
+	queuedFutures, err := r.Enqueue(queued)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, err = r.Runner().Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	for _, future := range []*job_with_generics.Future[int]{futures[1], queuedFutures[0]} {
+		future.Wait()
+		assert.True(t, future.IsCanceled())
+		assert.ErrorIs(t, future.Error(), runner.ErrStopped)
+	}
+	assert.Equal(t, delayed.ID(), job_with_generics.ID((<-done).ID))
+}
+
+func TestRunner_FuturesOfJobsQueuedAtStop(t *testing.T) {
+	r := runner_with_generics.New[int](runner.Config{Concurrency: 1})
+	r.Start()
+
+	blocking := &blockingTask{started: make(chan struct{})}
+	running, err := job_with_generics.New[int](blocking)
+	assert.NoError(t, err)
+	queued, err := job_with_generics.New[int](&doubleTask{n: 1})
+	assert.NoError(t, err)
+
+	futures, err := r.Enqueue(running, queued)
+	assert.NoError(t, err)
+	<-blocking.started
+
+	r.Stop()
+
+	futures[1].Wait()
+	assert.True(t, futures[1].IsCanceled())
+	assert.ErrorIs(t, futures[1].Error(), runner.ErrStopped)
+
+	futures[0].Wait()
+	assert.True(t, futures[0].IsCanceled())
+}
+
+func TestRunner_Scale(t *testing.T) {
+	r := runner_with_generics.New[int](runner.Config{Concurrency: 1})
+	r.Start()
+	defer r.Stop()
+
+	r.ScaleUp(2)
+	assert.Equal(t, 3, r.Status().Concurrency)
+
+	r.ScaleDown(1)
+	assert.Equal(t, 2, r.Status().Concurrency)
+}
+
+func TestWrap(t *testing.T) {
+	shared := runner.New(runner.Config{Concurrency: 1})
+	ints := runner_with_generics.Wrap[int](shared)
+	strings := runner_with_generics.Wrap[string](shared)
+	assert.Equal(t, shared, ints.Runner())
+
+	ints.Start()
+	defer strings.Stop()
+
+	number, err := job_with_generics.New[int](&doubleTask{n: 1})
+	assert.NoError(t, err)
+	text, err := job_with_generics.New[string](&textTask{})
+	assert.NoError(t, err)
+
+	numbers, err := ints.Enqueue(number)
+	assert.NoError(t, err)
+	texts, err := strings.Enqueue(text)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, numbers[0].Result())
+	assert.Equal(t, "text", texts[0].Result())
+}
+
+func waitState[T any](
+	t *testing.T, r *runner_with_generics.Runner[T], id job_with_generics.ID, state runner.State,
+) runner.JobInfo {
+	t.Helper()
+
+	for {
+		info, ok := r.Get(id)
+		if !assert.True(t, ok) || info.State == state {
+			return info
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type doubleTask struct {
+	n int
+}
+
+func (t *doubleTask) Run(*job_with_generics.Job[int]) (int, error) {
+	if t.n < 0 {
+		return 0, errors.New("negative number")
+	}
+	return t.n * 2, nil
+}
+
+type textTask struct{}
+
+func (textTask) Run(*job_with_generics.Job[string]) (string, error) {
+	return "text", nil
+}
+
+type blockingTask struct {
+	started      chan struct{}
+	canceledWith error
+}
+
+func (t *blockingTask) Run(j *job_with_generics.Job[int]) (int, error) {
+	close(t.started)
+	for !j.IsCanceled() {
+		time.Sleep(time.Millisecond)
+	}
+	return 0, nil
+}
+
+func (t *blockingTask) OnCancel(err error) {
+	t.canceledWith = err
+}
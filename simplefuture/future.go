@@ -4,6 +4,8 @@ package simplefuture
 import (
 	"errors"
 	"sync"
+
+	"github.com/andreiavrammsd/workexec/recovery"
 )
 
 // Future represents a task which executes async work.
@@ -13,6 +15,8 @@ type Future struct {
 	err      error
 	canceled bool
 	on       bool
+	// panicHandler is notified when the task panics
+	panicHandler PanicHandler
 	sync.RWMutex
 }
 
@@ -64,7 +68,7 @@ func (f *Future) IsCanceled() bool {
 func (f *Future) run() {
 	defer close(f.done)
 
-	f.err = f.task.Run(f.IsCanceled)
+	f.err = f.call()
 
 	if task, ok := f.task.(CanceledTask); ok {
 		f.RLock()
@@ -89,8 +93,19 @@ func (f *Future) run() {
 	}
 }
 
+// call runs the task and converts a panic into a PanicError.
+func (f *Future) call() (err error) {
+	defer func() {
+		if perr := recovery.Recovered(recover(), f.panicHandler); perr != nil {
+			err = perr
+		}
+	}()
+
+	return f.task.Run(f.IsCanceled)
+}
+
 // New creates a new future with a given task.
-func New(task Task) (*Future, error) {
+func New(task Task, opts ...Option) (*Future, error) {
 	if task == nil {
 		return nil, errors.New("nil task passed")
 	}
@@ -99,6 +114,9 @@ func New(task Task) (*Future, error) {
 		task: task,
 		done: make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(future)
+	}
 
 	return future, nil
 }
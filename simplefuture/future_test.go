@@ -4,6 +4,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/andreiavrammsd/workexec/recovery"
 	"github.com/andreiavrammsd/workexec/simplefuture"
 
 	"github.com/stretchr/testify/assert"
@@ -133,3 +134,34 @@ func (e *eventsTask) Run(func() bool) error {
 	}
 	return nil
 }
+
+func TestFuture_Panic(t *testing.T) {
+	task := &panicTask{}
+	var handled *simplefuture.PanicError
+	taskFuture, err := simplefuture.New(task, simplefuture.WithPanicHandler(func(err *simplefuture.PanicError) {
+		handled = err
+	}))
+	assert.NoError(t, err)
+
+	taskFuture.Run()
+	taskFuture.Wait()
+
+	var panicErr *recovery.PanicError
+	assert.True(t, errors.As(taskFuture.Error(), &panicErr))
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.Equal(t, taskFuture.Error(), task.err)
+	assert.Same(t, panicErr, handled)
+}
+
+type panicTask struct {
+	err error
+}
+
+func (p *panicTask) Run(func() bool) error {
+	panic("boom")
+}
+
+func (p *panicTask) OnError(err error) {
+	p.err = err
+}
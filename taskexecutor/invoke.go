@@ -0,0 +1,202 @@
+package taskexecutor
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrNoFutures is the error of InvokeAny called without futures.
+var ErrNoFutures = errors.New("no futures passed")
+
+// AggregateError is the error of InvokeAny when all futures failed. Errors are in the order of the futures.
+type AggregateError struct {
+	Errors []error
+}
+
+func (e *AggregateError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return "all futures failed: " + strings.Join(messages, "; ")
+}
+
+func (e *AggregateError) Unwrap() []error {
+	return e.Errors
+}
+
+// Result is the outcome of an invoked future.
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// InvokeAll submits the futures and blocks until all of them are done, returning their results in order.
+// Submitting blocks while the queue is full. If the context is done or the executor stops before all futures
+// are done, the futures are canceled and the error is returned.
+func (te *TaskExecutor) InvokeAll(ctx context.Context, futures ...Future) ([]Result, error) {
+	invoked, err := te.invoke(ctx, futures)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(invoked))
+	for i, inv := range invoked {
+		if err := te.await(ctx, inv); err != nil {
+			cancelAll(invoked)
+			return nil, err
+		}
+
+		results[i].Value, results[i].Err = inv.future.Result()
+	}
+
+	return results, nil
+}
+
+// InvokeAny submits the futures and returns the result of the first one which succeeds, canceling the others.
+// If all futures fail, the error is an AggregateError. Submitting blocks while the queue is full.
+// If the context is done or the executor stops first, the futures are canceled and the error is returned.
+func (te *TaskExecutor) InvokeAny(ctx context.Context, futures ...Future) (interface{}, error) {
+	if len(futures) == 0 {
+		return nil, ErrNoFutures
+	}
+
+	invoked, err := te.invoke(ctx, futures)
+	if err != nil {
+		return nil, err
+	}
+	defer cancelAll(invoked)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		index  int
+		result Result
+		err    error
+	}
+
+	outcomes := make(chan outcome, len(invoked))
+	for i, inv := range invoked {
+		go func(i int, inv *invocation) {
+			o := outcome{index: i, err: te.await(ctx, inv)}
+			if o.err == nil {
+				o.result.Value, o.result.Err = inv.future.Result()
+			}
+			outcomes <- o
+		}(i, inv)
+	}
+
+	errs := make([]error, len(invoked))
+	for range invoked {
+		o := <-outcomes
+		if o.err != nil {
+			return nil, o.err
+		}
+		if o.result.Err == nil {
+			return o.result.Value, nil
+		}
+		errs[o.index] = o.result.Err
+	}
+
+	return nil, &AggregateError{Errors: errs}
+}
+
+// invoke submits the futures. If one cannot be submitted, the ones before it are canceled.
+func (te *TaskExecutor) invoke(ctx context.Context, futures []Future) ([]*invocation, error) {
+	invoked := make([]*invocation, 0, len(futures))
+
+	for _, future := range futures {
+		if future == nil {
+			cancelAll(invoked)
+			return nil, errors.New("nil future passed")
+		}
+
+		inv := &invocation{future: future, done: make(chan struct{}), exited: te.exited}
+		if err := te.submit(ctx, submission{future: inv}); err != nil {
+			cancelAll(invoked)
+			return nil, err
+		}
+
+		invoked = append(invoked, inv)
+	}
+
+	return invoked, nil
+}
+
+// await blocks until an invoked future is done, the context is done or the routines exited without running it.
+func (te *TaskExecutor) await(ctx context.Context, inv *invocation) error {
+	select {
+	case <-inv.done:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-te.exited:
+		// The routines exit after the futures they run are done
+		select {
+		case <-inv.done:
+			return nil
+		default:
+			return ErrStopped
+		}
+	}
+}
+
+func cancelAll(invoked []*invocation) {
+	for _, inv := range invoked {
+		inv.future.Cancel()
+	}
+}
+
+// invocation is a submitted future which tells when it was run by the executor.
+// A future which was not run yet is not waited by its Wait, so it cannot be used for this.
+// A future which the executor never ran is resolved with ErrStopped.
+type invocation struct {
+	future Future
+	done   chan struct{}
+	exited <-chan struct{}
+}
+
+// Run runs the future until it is done. The executor calls Wait after Run, which returns right away.
+func (i *invocation) Run() {
+	defer close(i.done)
+
+	i.future.Run()
+	i.future.Wait()
+}
+
+func (i *invocation) Wait() {
+	i.ran()
+}
+
+func (i *invocation) Cancel() {
+	i.future.Cancel()
+}
+
+func (i *invocation) IsCanceled() bool {
+	return i.future.IsCanceled()
+}
+
+func (i *invocation) Result() (interface{}, error) {
+	if !i.ran() {
+		return nil, ErrStopped
+	}
+	return i.future.Result()
+}
+
+// ran blocks until the future was run or the routines exited without running it.
+func (i *invocation) ran() bool {
+	select {
+	case <-i.done:
+		return true
+	case <-i.exited:
+		// The routines exit after the futures they run are done
+		select {
+		case <-i.done:
+			return true
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package taskexecutor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/future"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskExecutor_InvokeAll(t *testing.T) {
+	// The batch is larger than the queue
+	taskExecutor := New(Config{Concurrency: 2, QueueSize: 1})
+	taskExecutor.Start()
+
+	results, err := taskExecutor.InvokeAll(
+		context.Background(),
+		newTestTaskFuture(t, func(func() bool) (interface{}, error) { return 1, nil }),
+		newTestTaskFuture(t, func(func() bool) (interface{}, error) { return nil, errors.New("failed") }),
+		newTestTaskFuture(t, func(func() bool) (interface{}, error) { return 3, nil }),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{{Value: 1}, {Err: errors.New("failed")}, {Value: 3}}, results)
+
+	taskExecutor.Stop()
+	taskExecutor.Wait()
+
+	_, err = taskExecutor.InvokeAll(context.Background(), newTestTaskFuture(t, nil))
+	assert.ErrorIs(t, err, ErrStopped)
+}
+
+func TestTaskExecutor_InvokeAllStopWithoutStart(t *testing.T) {
+	taskExecutor := New(Config{Concurrency: 1})
+
+	errs := make(chan error)
+	go func() {
+		_, err := taskExecutor.InvokeAll(context.Background(), newTestTaskFuture(t, nil))
+		errs <- err
+	}()
+	for taskExecutor.Status().QueuedTasks != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// No routine is left to run the queued future
+	taskExecutor.Stop()
+	assert.ErrorIs(t, <-errs, ErrStopped)
+	taskExecutor.Wait()
+}
+
+func TestTaskExecutor_InvokeAllContext(t *testing.T) {
+	taskExecutor := New(Config{Concurrency: 1})
+	taskExecutor.Start()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := newTestTaskFuture(t, func(isCanceled func() bool) (interface{}, error) {
+		cancel()
+		for !isCanceled() {
+			time.Sleep(time.Millisecond)
+		}
+		return nil, nil
+	})
+
+	results, err := taskExecutor.InvokeAll(ctx, blocked)
+	assert.Nil(t, results)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, blocked.IsCanceled())
+
+	taskExecutor.Stop()
+	taskExecutor.Wait()
+}
+
+func TestTaskExecutor_InvokeAny(t *testing.T) {
+	taskExecutor := New(Config{Concurrency: 3})
+	taskExecutor.Start()
+
+	slow := newTestTaskFuture(t, func(isCanceled func() bool) (interface{}, error) {
+		for !isCanceled() {
+			time.Sleep(time.Millisecond)
+		}
+		return nil, errors.New("canceled")
+	})
+
+	result, err := taskExecutor.InvokeAny(
+		context.Background(),
+		newTestTaskFuture(t, func(func() bool) (interface{}, error) { return nil, errors.New("failed") }),
+		slow,
+		newTestTaskFuture(t, func(func() bool) (interface{}, error) { return 3, nil }),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result)
+	assert.True(t, slow.IsCanceled())
+
+	result, err = taskExecutor.InvokeAny(
+		context.Background(),
+		newTestTaskFuture(t, func(func() bool) (interface{}, error) { return nil, errors.New("first") }),
+		newTestTaskFuture(t, func(func() bool) (interface{}, error) { return nil, errors.New("second") }),
+	)
+	assert.Nil(t, result)
+	assert.Equal(t, &AggregateError{Errors: []error{errors.New("first"), errors.New("second")}}, err)
+
+	_, err = taskExecutor.InvokeAny(context.Background())
+	assert.ErrorIs(t, err, ErrNoFutures)
+
+	taskExecutor.Stop()
+	taskExecutor.Wait()
+}
+
+type testTask func(isCanceled func() bool) (interface{}, error)
+
+func (t testTask) Run(isCanceled func() bool) (interface{}, error) {
+	if t == nil {
+		return nil, nil
+	}
+	return t(isCanceled)
+}
+
+func newTestTaskFuture(t *testing.T, run testTask) *future.Future {
+	t.Helper()
+
+	f, err := future.New(run)
+	assert.NoError(t, err)
+	return f
+}
@@ -0,0 +1,9 @@
+package taskexecutor
+
+import "github.com/andreiavrammsd/workexec/recovery"
+
+// PanicError is the error of a future which panicked.
+type PanicError = recovery.PanicError
+
+// PanicHandler is notified when a future panics while the executor runs it.
+type PanicHandler = recovery.PanicHandler
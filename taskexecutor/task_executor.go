@@ -2,16 +2,28 @@
 package taskexecutor
 
 import (
+	"context"
 	"errors"
 	"math"
 	"runtime"
 	"sync"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/ratelimit"
+	"github.com/andreiavrammsd/workexec/recovery"
 )
 
 const (
 	defaultQueueSize = 1024
+
+	// cancelPollInterval is how often a future waiting for the rate limiter is checked for cancellation
+	cancelPollInterval = time.Millisecond * 10
 )
 
+// ErrStopped is returned when a task is submitted to a stopped executor,
+// or when the executor stopped before running an invoked task.
+var ErrStopped = errors.New("executor is stopped")
+
 // Config allows setup of executor.
 type Config struct {
 	// Concurrency is the number of routines the executor will start working on.
@@ -19,38 +31,72 @@ type Config struct {
 
 	// QueueSize is the number of tasks accepted before blocking.
 	QueueSize uint
+
+	// RateLimiter limits how often routines start tasks, globally and by the key the tasks are submitted with.
+	// A task waits for the limiter on a routine and can be canceled while waiting. Nil means no limit.
+	RateLimiter *ratelimit.Limiter
+
+	// PanicHandler is notified when a future panics. The routine recovers and keeps working.
+	PanicHandler PanicHandler
+}
+
+// Status represents the current state of the executor.
+type Status struct {
+	Concurrency  uint
+	RunningTasks uint
+	QueuedTasks  int
+
+	// ThrottledTasks is the number of tasks waiting for the rate limiter. They are counted as running tasks too.
+	ThrottledTasks uint
 }
 
 // TaskExecutor represents the executor instance.
 type TaskExecutor struct {
 	concurrency  uint
-	queue        chan Future
+	queue        chan submission
 	wait         chan struct{}
 	stop         chan struct{}
+	exited       chan struct{}
+	exit         sync.Once
+	workers      uint
 	runningTasks uint
+	throttled    uint
+	limiter      *ratelimit.Limiter
+	onPanic      PanicHandler
 	lock         sync.RWMutex
 	stopped      bool
 }
 
-// Start opens the working routines.
+// Start opens the working routines. A stopped executor is not started again.
 func (te *TaskExecutor) Start() {
+	te.lock.Lock()
+	if te.stopped {
+		te.lock.Unlock()
+		return
+	}
+	te.workers += te.concurrency
+	te.lock.Unlock()
+
 	for i := uint(0); i < te.concurrency; i++ {
 		go te.run()
 	}
 }
 
-// Stop asks the working routines to stop after tasks are finished.
+// Stop asks the working routines to stop after tasks are finished,
+// however many times the executor was started.
 func (te *TaskExecutor) Stop() {
 	te.lock.Lock()
+	defer te.lock.Unlock()
+
 	if te.stopped {
-		te.lock.Unlock()
 		return
 	}
 	te.stopped = true
-	te.lock.Unlock()
+	close(te.stop)
 
-	for i := uint(0); i < te.concurrency; i++ {
-		te.stop <- struct{}{}
+	// An executor which was never started has no routines to run the queued tasks
+	if te.workers == 0 {
+		te.exitAll()
 	}
 }
 
@@ -61,36 +107,58 @@ func (te *TaskExecutor) Wait() {
 
 // Submit puts a task into the executor queue.
 func (te *TaskExecutor) Submit(future Future) error {
+	return te.SubmitWithKey("", future)
+}
+
+// SubmitWithKey puts a task into the executor queue. The task is rate limited by given key,
+// like a tenant or a target host. An empty key means only the global limit.
+func (te *TaskExecutor) SubmitWithKey(key string, future Future) error {
+	return te.submit(context.Background(), submission{future: future, key: key})
+}
+
+// submit puts a task into the queue, blocking while the queue is full until the context is done
+// or the routines exited.
+func (te *TaskExecutor) submit(ctx context.Context, s submission) error {
 	te.lock.RLock()
 	if te.stopped {
 		te.lock.RUnlock()
-		return errors.New("executor is stopped")
+		return ErrStopped
 	}
 	te.lock.RUnlock()
 
-	te.queue <- future
+	select {
+	case te.queue <- s:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-te.exited:
+		return ErrStopped
+	}
+}
 
-	return nil
+// Status returns executor state.
+func (te *TaskExecutor) Status() Status {
+	te.lock.RLock()
+	defer te.lock.RUnlock()
+	return Status{
+		Concurrency:    te.concurrency,
+		RunningTasks:   te.runningTasks,
+		QueuedTasks:    len(te.queue),
+		ThrottledTasks: te.throttled,
+	}
 }
 
 func (te *TaskExecutor) run() {
+	defer te.exitRoutine()
+
 	for {
 		select {
-		case future := <-te.queue:
-			te.lock.Lock()
-			te.runningTasks++
-			te.lock.Unlock()
-
-			future.Run()
-			future.Wait()
-
-			te.lock.Lock()
-			te.runningTasks--
-			te.lock.Unlock()
+		case s := <-te.queue:
+			te.execute(s)
 		case <-te.stop:
 			te.lock.RLock()
 			if te.stopped && te.runningTasks == 0 {
-				te.wait <- struct{}{}
+				te.release()
 			}
 			te.lock.RUnlock()
 
@@ -99,6 +167,95 @@ func (te *TaskExecutor) run() {
 	}
 }
 
+// exitRoutine marks the end of a routine. When all routines of a stopped executor exited,
+// the tasks still queued are never run.
+func (te *TaskExecutor) exitRoutine() {
+	te.lock.Lock()
+	defer te.lock.Unlock()
+
+	te.workers--
+	if te.workers == 0 && te.stopped {
+		te.exitAll()
+	}
+}
+
+// exitAll marks that no routine is left to run the queued tasks and releases Wait.
+func (te *TaskExecutor) exitAll() {
+	te.exit.Do(func() {
+		close(te.exited)
+	})
+	te.release()
+}
+
+// release unblocks Wait, without blocking when it is already unblocked.
+func (te *TaskExecutor) release() {
+	select {
+	case te.wait <- struct{}{}:
+	default:
+	}
+}
+
+// execute runs a future and waits for it. A panic is recovered, so the routine keeps working.
+func (te *TaskExecutor) execute(s submission) {
+	te.lock.Lock()
+	te.runningTasks++
+	te.lock.Unlock()
+
+	defer func() {
+		te.lock.Lock()
+		te.runningTasks--
+		te.lock.Unlock()
+
+		recovery.Recovered(recover(), te.onPanic)
+	}()
+
+	te.throttle(s)
+
+	s.future.Run()
+	s.future.Wait()
+}
+
+// throttle waits for the rate limiter until the task can start or its future is canceled.
+// A future which was canceled while waiting is still run, to report it.
+func (te *TaskExecutor) throttle(s submission) {
+	if te.limiter == nil || te.limiter.Allow(s.key) {
+		return
+	}
+
+	te.lock.Lock()
+	te.throttled++
+	te.lock.Unlock()
+
+	defer func() {
+		te.lock.Lock()
+		te.throttled--
+		te.lock.Unlock()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// A future is canceled by a flag, so it is polled while waiting
+	go func() {
+		ticker := time.NewTicker(cancelPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if s.future.IsCanceled() {
+					cancel()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	te.limiter.Wait(ctx, s.key) // nolint:errcheck
+}
+
 // New creates a new task executor.
 func New(c Config) *TaskExecutor {
 	if c.Concurrency == 0 {
@@ -110,8 +267,17 @@ func New(c Config) *TaskExecutor {
 
 	return &TaskExecutor{
 		concurrency: c.Concurrency,
-		queue:       make(chan Future, c.QueueSize),
+		queue:       make(chan submission, c.QueueSize),
 		wait:        make(chan struct{}, c.Concurrency),
-		stop:        make(chan struct{}, c.Concurrency),
+		stop:        make(chan struct{}),
+		exited:      make(chan struct{}),
+		limiter:     c.RateLimiter,
+		onPanic:     c.PanicHandler,
 	}
 }
+
+// submission is a future waiting in the queue.
+type submission struct {
+	future Future
+	key    string
+}
@@ -61,6 +61,34 @@ func Example() {
 	// on cancel
 }
 
+func ExampleSubmitTask() {
+	taskExecutor := taskexecutor_with_generics.New(taskexecutor_with_generics.Config{Concurrency: 2})
+	taskExecutor.Start()
+
+	// One executor runs futures of different result types, which are returned typed
+	number, err := taskexecutor_with_generics.SubmitTask[uint](taskExecutor, &task{n: 3})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	text, err := taskexecutor_with_generics.SubmitTask[string](taskExecutor, &anotherTask{input: "x"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	numberResult, _ := number.Result()
+	textResult, _ := text.Result()
+	fmt.Println(numberResult+1, textResult+"!")
+
+	taskExecutor.Stop()
+	taskExecutor.Wait()
+
+	// Unordered output:
+	// on success: 3 -> 11
+	// on success: x -> xx
+	// 12 xx!
+}
+
 type task struct {
 	n uint
 }
@@ -4,13 +4,20 @@ import "github.com/andreiavrammsd/workexec/future_with_generics"
 
 // Future represents a task which executes async work.
 type Future[T any] interface {
+	Runnable
+	Result() (T, error)
+}
+
+// Runnable is what the executor needs of a future. A Future of any result type is Runnable,
+// so one executor runs futures of different result types.
+type Runnable interface {
 	Run()
 	Wait()
 	Cancel()
-	Result() (T, error)
 	IsCanceled() bool
 }
 
+// AnyFuture adapts a future to Future[any], for code which handles futures of different result types together.
 type AnyFuture[T any] struct {
 	Future Future[T]
 }
@@ -35,6 +42,7 @@ func (a *AnyFuture[T]) Result() (any, error) {
 	return a.Future.Result()
 }
 
+// NewFuture creates the future of given task as Future[any]. SubmitTask keeps the result type.
 func NewFuture[T any](task future_with_generics.Task[T]) (Future[any], error) {
 	future, err := future_with_generics.New(task)
 	if err != nil {
@@ -44,7 +52,59 @@ func NewFuture[T any](task future_with_generics.Task[T]) (Future[any], error) {
 	return &AnyFuture[T]{Future: future}, nil
 }
 
-// func WrapFuture[T any](future Future[T]) Future[any] {
-// 	future_with_generics.New[uint](&task{n: 0})
-// 	return &AnyFuture[T]{Future: future}
-// }
+// WrapFuture adapts an already created future to Future[any].
+func WrapFuture[T any](future Future[T]) Future[any] {
+	return &AnyFuture[T]{Future: future}
+}
+
+// submitted is the future returned by Submit. Its Wait and Result block until the executor ran the future,
+// so they can be called right after submitting. A future which the executor never ran is resolved with ErrStopped.
+type submitted[T any] struct {
+	future Future[T]
+	done   chan struct{}
+	exited <-chan struct{}
+}
+
+// Run runs the future until it is done. The executor calls Wait after Run, which returns right away.
+func (s *submitted[T]) Run() {
+	defer close(s.done)
+
+	s.future.Run()
+	s.future.Wait()
+}
+
+func (s *submitted[T]) Wait() {
+	s.ran()
+}
+
+func (s *submitted[T]) Cancel() {
+	s.future.Cancel()
+}
+
+func (s *submitted[T]) IsCanceled() bool {
+	return s.future.IsCanceled()
+}
+
+func (s *submitted[T]) Result() (T, error) {
+	if !s.ran() {
+		var zero T
+		return zero, ErrStopped
+	}
+	return s.future.Result()
+}
+
+// ran blocks until the future was run or the routines exited without running it.
+func (s *submitted[T]) ran() bool {
+	select {
+	case <-s.done:
+		return true
+	case <-s.exited:
+		// The routines exit after the futures they run are done
+		select {
+		case <-s.done:
+			return true
+		default:
+			return false
+		}
+	}
+}
@@ -3,8 +3,13 @@ package taskexecutor_with_generics
 import (
 	"math"
 	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/andreiavrammsd/workexec/clock"
+	"github.com/andreiavrammsd/workexec/future_with_generics"
+	"github.com/andreiavrammsd/workexec/ratelimit"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,3 +19,261 @@ func TestNew(t *testing.T) {
 
 	assert.Equal(t, uint(math.Max(1, float64(runtime.NumCPU())-1)), taskExecutor.concurrency)
 }
+
+func TestTaskExecutor_Panic(t *testing.T) {
+	handled := make(chan *PanicError, 1)
+	taskExecutor := New(Config{
+		Concurrency: 1,
+		PanicHandler: func(err *PanicError) {
+			handled <- err
+		},
+	})
+	taskExecutor.Start()
+
+	next := &testFuture{ran: make(chan struct{})}
+	assert.NoError(t, taskExecutor.Submit(&testFuture{panics: true}))
+	assert.NoError(t, taskExecutor.Submit(next))
+
+	err := <-handled
+	assert.Equal(t, "boom", err.Value)
+	assert.NotEmpty(t, err.Stack)
+
+	// The only routine survived the panic
+	<-next.ran
+
+	taskExecutor.Stop()
+	taskExecutor.Wait()
+	assert.Equal(t, uint(0), taskExecutor.runningTasks)
+}
+
+type testFuture struct {
+	panics bool
+	ran    chan struct{}
+}
+
+var _ Future[any] = (*testFuture)(nil)
+
+func (f *testFuture) Run() {
+	if f.panics {
+		panic("boom")
+	}
+	close(f.ran)
+}
+
+func (f *testFuture) Wait()                {}
+func (f *testFuture) Cancel()              {}
+func (f *testFuture) Result() (any, error) { return nil, nil }
+func (f *testFuture) IsCanceled() bool     { return false }
+
+func TestTaskExecutor_Status(t *testing.T) {
+	taskExecutor := New(Config{Concurrency: 2})
+	assert.NoError(t, taskExecutor.Submit(&testFuture{ran: make(chan struct{})}))
+
+	assert.Equal(t, Status{Concurrency: 2, QueuedTasks: 1}, taskExecutor.Status())
+}
+
+func TestTaskExecutor_RateLimiter(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	limiter := ratelimit.New(ratelimit.Config{PerKey: ratelimit.Limit{Rate: 1}, Clock: c})
+	assert.True(t, limiter.Allow("host"))
+
+	taskExecutor := New(Config{Concurrency: 2, RateLimiter: limiter})
+	taskExecutor.Start()
+
+	limited := &testFuture{ran: make(chan struct{})}
+	assert.NoError(t, taskExecutor.SubmitWithKey("host", limited))
+
+	waitThrottled(t, taskExecutor, 1)
+
+	other := &testFuture{ran: make(chan struct{})}
+	assert.NoError(t, taskExecutor.SubmitWithKey("other", other))
+	<-other.ran
+
+	select {
+	case <-limited.ran:
+		t.Fatal("expected task to wait for the rate limiter")
+	default:
+	}
+
+	for c.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	c.Advance(time.Second)
+	<-limited.ran
+
+	taskExecutor.Stop()
+	taskExecutor.Wait()
+	assert.Equal(t, uint(0), taskExecutor.Status().ThrottledTasks)
+}
+
+func TestTaskExecutor_RateLimiterCancel(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	limiter := ratelimit.New(ratelimit.Config{Global: ratelimit.Limit{Rate: 1}, Clock: c})
+	assert.True(t, limiter.Allow(""))
+
+	taskExecutor := New(Config{Concurrency: 1, RateLimiter: limiter})
+	taskExecutor.Start()
+
+	future := &cancelableFuture{testFuture: testFuture{ran: make(chan struct{})}}
+	assert.NoError(t, taskExecutor.Submit(future))
+
+	waitThrottled(t, taskExecutor, 1)
+	future.Cancel()
+
+	// The canceled future is run without waiting for the rate limiter
+	<-future.ran
+	assert.True(t, future.IsCanceled())
+
+	taskExecutor.Stop()
+	taskExecutor.Wait()
+}
+
+type cancelableFuture struct {
+	testFuture
+	canceled atomic.Bool
+}
+
+func (f *cancelableFuture) Cancel()          { f.canceled.Store(true) }
+func (f *cancelableFuture) IsCanceled() bool { return f.canceled.Load() }
+
+func waitThrottled(t *testing.T, taskExecutor *TaskExecutor, count uint) {
+	t.Helper()
+
+	for taskExecutor.Status().ThrottledTasks != count {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	taskExecutor := New(Config{Concurrency: 2})
+	taskExecutor.Start()
+
+	number, err := SubmitTask[int](taskExecutor, &valueTask[int]{value: 1})
+	assert.NoError(t, err)
+
+	created, err := future_with_generics.New[string](&valueTask[string]{value: "text"})
+	assert.NoError(t, err)
+	text, err := Submit[string](taskExecutor, created)
+	assert.NoError(t, err)
+
+	numberResult, err := number.Result()
+	assert.Equal(t, 1, numberResult)
+	assert.NoError(t, err)
+
+	textResult, err := text.Result()
+	assert.Equal(t, "text", textResult)
+	assert.NoError(t, err)
+
+	_, err = Submit[int](taskExecutor, nil)
+	assert.Error(t, err)
+	_, err = SubmitTask[int](taskExecutor, nil)
+	assert.Error(t, err)
+
+	taskExecutor.Stop()
+	taskExecutor.Wait()
+
+	_, err = SubmitTask[int](taskExecutor, &valueTask[int]{value: 1})
+	assert.Error(t, err)
+}
+
+func TestSubmit_NotRun(t *testing.T) {
+	taskExecutor := New(Config{Concurrency: 1, PanicHandler: func(*PanicError) {}})
+	taskExecutor.Start()
+
+	// A future which panicked was run
+	panicked, err := Submit[any](taskExecutor, &testFuture{panics: true})
+	assert.NoError(t, err)
+	panicked.Wait()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	running, err := SubmitTask[int](taskExecutor, &waitTask{started: started, release: release})
+	assert.NoError(t, err)
+	<-started
+
+	queued, err := SubmitTask[int](taskExecutor, &valueTask[int]{value: 1})
+	assert.NoError(t, err)
+	taskExecutor.Stop()
+
+	// The routine stops before running the queued future
+	<-taskExecutor.queue
+	close(release)
+	taskExecutor.Wait()
+
+	result, err := running.Result()
+	assert.Equal(t, 1, result)
+	assert.NoError(t, err)
+
+	queued.Wait()
+	_, err = queued.Result()
+	assert.ErrorIs(t, err, ErrStopped)
+
+	_, err = SubmitTask[int](taskExecutor, &valueTask[int]{value: 1})
+	assert.ErrorIs(t, err, ErrStopped)
+}
+
+func TestSubmit_StopWithoutStart(t *testing.T) {
+	taskExecutor := New(Config{Concurrency: 1})
+
+	queued, err := SubmitTask[int](taskExecutor, &valueTask[int]{value: 1})
+	assert.NoError(t, err)
+
+	// No routine is left to run the queued future
+	taskExecutor.Stop()
+	taskExecutor.Wait()
+
+	queued.Wait()
+	_, err = queued.Result()
+	assert.ErrorIs(t, err, ErrStopped)
+}
+
+func TestTaskExecutor_StartTwice(t *testing.T) {
+	taskExecutor := New(Config{Concurrency: 2})
+	taskExecutor.Start()
+	taskExecutor.Start()
+
+	// All routines of both starts stop
+	taskExecutor.Stop()
+	taskExecutor.Wait()
+	<-taskExecutor.exited
+	assert.Equal(t, uint(0), taskExecutor.workers)
+
+	// A stopped executor is not started again
+	taskExecutor.Start()
+	_, err := SubmitTask[int](taskExecutor, &valueTask[int]{value: 1})
+	assert.ErrorIs(t, err, ErrStopped)
+	assert.Equal(t, uint(0), taskExecutor.workers)
+}
+
+func TestWrapFuture(t *testing.T) {
+	created, err := future_with_generics.New[int](&valueTask[int]{value: 1})
+	assert.NoError(t, err)
+
+	wrapped := WrapFuture[int](created)
+	wrapped.Run()
+	wrapped.Wait()
+
+	result, err := wrapped.Result()
+	assert.Equal(t, 1, result)
+	assert.NoError(t, err)
+	assert.False(t, wrapped.IsCanceled())
+}
+
+type valueTask[T any] struct {
+	value T
+}
+
+func (t *valueTask[T]) Run(func() bool) (T, error) {
+	return t.value, nil
+}
+
+type waitTask struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (t *waitTask) Run(func() bool) (int, error) {
+	close(t.started)
+	<-t.release
+	return 1, nil
+}
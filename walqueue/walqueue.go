@@ -0,0 +1,716 @@
+// Package walqueue provides a durable runner queue, backed by a write-ahead log on disk.
+// Jobs which are pushed are kept until the runner acknowledges them, so they are replayed
+// when the queue is opened again after a restart or a crash. A job may run more than once
+// if the process stops after it ran but before it was acknowledged.
+//
+// The log is split into segment files. A new segment is started when the current one reaches
+// the segment size, and old segments are removed when all their jobs were acknowledged.
+//
+// Jobs are persisted with job.Registry Marshal. Job options (retry, timeout, deadline)
+// and the delayed jobs of the runner are not persisted.
+package walqueue
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+)
+
+const (
+	segmentSize   = 64 << 20
+	syncInterval  = time.Second
+	segmentSuffix = ".wal"
+	// headerSize is the size of the frame header: payload length and checksum
+	headerSize = 8
+)
+
+// SyncPolicy tells when writes are flushed to disk.
+type SyncPolicy int
+
+const (
+	// SyncAlways flushes after every write. No acknowledged push is lost, at the cost of throughput:
+	// the runner pushes and acknowledges jobs while holding its lock, so every enqueue and every finished job
+	// waits for a disk flush, and so do all the others meanwhile. It is the default.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval flushes periodically. The writes of the last interval can be lost on a crash.
+	SyncInterval
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+// Config allows setup of the queue.
+type Config struct {
+	// Dir is the directory of the log. It is created if it does not exist.
+	Dir string
+
+	// Registry marshals and unmarshals the jobs.
+	Registry *job.Registry
+
+	// SegmentSize is the size in bytes after which a new segment is started. Defaults to 64 MB.
+	SegmentSize int64
+
+	// Sync is the policy of flushing writes to disk. Defaults to SyncAlways, which is the safest and the slowest.
+	Sync SyncPolicy
+
+	// SyncInterval is the interval of flushing for SyncInterval. Defaults to one second.
+	SyncInterval time.Duration
+}
+
+type recordType uint8
+
+const (
+	pushRecord recordType = iota + 1
+	ackRecord
+)
+
+// record is an entry of the log.
+type record struct {
+	Type       recordType      `json:"type"`
+	Seq        uint64          `json:"seq"`
+	Priority   runner.Priority `json:"priority,omitempty"`
+	EnqueuedAt time.Time       `json:"enqueued_at,omitempty"`
+	Key        string          `json:"key,omitempty"`
+	Job        json.RawMessage `json:"job,omitempty"`
+}
+
+// segment is a file of the log.
+type segment struct {
+	index int
+	size  int64
+	// live is the number of pushed jobs in the segment which were not acknowledged
+	live int
+}
+
+// entry is a job which was not acknowledged.
+type entry struct {
+	runner.QueuedJob
+	seq     uint64
+	segment *segment
+	// frame is the encoded push record, written again on compaction
+	frame []byte
+}
+
+// logFile is the active segment, an *os.File.
+type logFile interface {
+	io.Writer
+	io.Seeker
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// Queue is a runner.Queue persisted to a write-ahead log.
+// Jobs are ordered by priority, FIFO inside the same priority.
+type Queue struct {
+	lock     sync.Mutex
+	dir      string
+	registry *job.Registry
+	maxSize  int64
+	sync     SyncPolicy
+
+	file     logFile
+	segments []*segment
+	seq      uint64
+	dirty    bool
+	closed   bool
+	// failed is set when a failed write could not be rolled back, the log cannot be appended to anymore
+	failed  error
+	stop    chan struct{}
+	stopped chan struct{}
+
+	pending  entries
+	inflight map[*job.Job][]*entry
+	counts   map[runner.Priority]int
+}
+
+// Push writes a job to the log and adds it to the queue.
+func (q *Queue) Push(qj runner.QueuedJob) error {
+	data, err := q.registry.Marshal(qj.Job)
+	if err != nil {
+		return err
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if err := q.writable(); err != nil {
+		return err
+	}
+
+	rec := record{
+		Type:       pushRecord,
+		Seq:        q.seq + 1,
+		Priority:   qj.Priority,
+		EnqueuedAt: qj.EnqueuedAt,
+		Key:        qj.Key,
+		Job:        data,
+	}
+	frame, err := encode(rec)
+	if err != nil {
+		return err
+	}
+
+	if err := q.write(frame); err != nil {
+		return err
+	}
+
+	q.seq++
+	active := q.active()
+	active.live++
+
+	heap.Push(&q.pending, &entry{QueuedJob: qj, seq: rec.Seq, segment: active, frame: frame})
+	q.counts[qj.Priority]++
+
+	// The job is pushed already. A segment which could not be rotated grows until the next write rotates it.
+	q.rotate() // nolint:errcheck
+
+	return nil
+}
+
+// Pop removes the next job from the queue. The job is kept in the log until it is acknowledged.
+func (q *Queue) Pop() (runner.QueuedJob, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.pending) == 0 {
+		return runner.QueuedJob{}, false
+	}
+
+	e := heap.Pop(&q.pending).(*entry) // nolint:errcheck
+	q.inflight[e.Job] = append(q.inflight[e.Job], e)
+
+	q.counts[e.Priority]--
+	if q.counts[e.Priority] == 0 {
+		delete(q.counts, e.Priority)
+	}
+
+	return e.QueuedJob, true
+}
+
+// Ack removes a popped job from the log.
+func (q *Queue) Ack(qj runner.QueuedJob) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if err := q.writable(); err != nil {
+		return err
+	}
+
+	popped := q.inflight[qj.Job]
+	if len(popped) == 0 {
+		return fmt.Errorf("job %s was not popped", qj.Job.ID())
+	}
+	e := popped[0]
+
+	frame, err := encode(record{Type: ackRecord, Seq: e.seq})
+	if err != nil {
+		return err
+	}
+	if err := q.write(frame); err != nil {
+		return err
+	}
+
+	if len(popped) == 1 {
+		delete(q.inflight, qj.Job)
+	} else {
+		q.inflight[qj.Job] = popped[1:]
+	}
+	e.segment.live--
+
+	// The job is acknowledged already. Segments which could not be rotated or removed are handled
+	// by the next write or acknowledgment.
+	q.rotate()      // nolint:errcheck
+	q.removeAcked() // nolint:errcheck
+
+	return nil
+}
+
+// Len returns the number of jobs waiting.
+func (q *Queue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return len(q.pending)
+}
+
+// Counts returns the number of jobs waiting by priority.
+func (q *Queue) Counts() map[runner.Priority]int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	counts := make(map[runner.Priority]int, len(q.counts))
+	for p, c := range q.counts {
+		counts[p] = c
+	}
+	return counts
+}
+
+// Compact rewrites the jobs which were not acknowledged to a new segment and removes all the others.
+func (q *Queue) Compact() error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if err := q.writable(); err != nil {
+		return err
+	}
+
+	live := make([]*entry, 0, len(q.pending)+len(q.inflight))
+	live = append(live, q.pending...)
+	for _, popped := range q.inflight {
+		live = append(live, popped...)
+	}
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].seq < live[j].seq
+	})
+
+	if err := q.startSegment(); err != nil {
+		return err
+	}
+
+	active := q.active()
+	for _, e := range live {
+		if err := q.write(e.frame); err != nil {
+			return err
+		}
+		e.segment = active
+	}
+	active.live = len(live)
+
+	if err := q.flush(); err != nil {
+		return err
+	}
+
+	// Older segments are removed only after the live jobs are safely in the new one.
+	// If this is interrupted, the duplicated pushes are merged by sequence on replay.
+	for _, s := range q.segments[:len(q.segments)-1] {
+		if err := os.Remove(q.path(s.index)); err != nil {
+			return err
+		}
+	}
+	q.segments = []*segment{active}
+
+	return nil
+}
+
+// Close flushes and closes the log. The queue cannot be used after it is closed.
+func (q *Queue) Close() error {
+	q.lock.Lock()
+	if q.closed {
+		q.lock.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.lock.Unlock()
+
+	if q.stop != nil {
+		close(q.stop)
+		<-q.stopped
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	var err error
+	if q.sync != SyncNever {
+		err = q.file.Sync()
+	}
+	if closeErr := q.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// writable returns an error if the log cannot be written to.
+func (q *Queue) writable() error {
+	if q.closed {
+		return errors.New("queue is closed")
+	}
+
+	return q.failed
+}
+
+// write appends a frame to the active segment.
+// A failed write is rolled back, as a partial frame would hide the frames written after it on replay,
+// and a frame which could not be synced would be replayed for a write reported as failed.
+// If the rollback fails too, the queue is failed and rejects all writes.
+func (q *Queue) write(frame []byte) error {
+	offset := q.active().size
+
+	if _, err := q.file.Write(frame); err != nil {
+		q.abort(offset)
+		return fmt.Errorf("cannot write to log: %w", err)
+	}
+
+	switch q.sync {
+	case SyncAlways:
+		if err := q.file.Sync(); err != nil {
+			q.abort(offset)
+			return fmt.Errorf("cannot sync log: %w", err)
+		}
+	case SyncInterval:
+		q.dirty = true
+	case SyncNever:
+	}
+
+	q.active().size += int64(len(frame))
+
+	return nil
+}
+
+// abort rolls back a failed write, failing the queue if it cannot.
+func (q *Queue) abort(offset int64) {
+	if err := q.rollback(offset); err != nil {
+		q.failed = fmt.Errorf("queue failed: cannot roll back write: %w", err)
+	}
+}
+
+// rollback removes what was written to the active segment after given offset.
+func (q *Queue) rollback(offset int64) error {
+	if err := q.file.Truncate(offset); err != nil {
+		return err
+	}
+
+	_, err := q.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// flush syncs the active segment unless syncing is left to the operating system.
+func (q *Queue) flush() error {
+	if q.sync == SyncNever {
+		return nil
+	}
+
+	if err := q.file.Sync(); err != nil {
+		return fmt.Errorf("cannot sync log: %w", err)
+	}
+	q.dirty = false
+
+	return nil
+}
+
+// rotate starts a new segment if the active one is full.
+func (q *Queue) rotate() error {
+	if q.active().size < q.maxSize {
+		return nil
+	}
+
+	return q.startSegment()
+}
+
+// startSegment closes the active segment and creates a new one.
+func (q *Queue) startSegment() error {
+	if err := q.flush(); err != nil {
+		return err
+	}
+
+	// The new segment is opened first, so the active one is still usable if it cannot be
+	s := &segment{index: q.active().index + 1}
+	file, err := os.OpenFile(q.path(s.index), os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	previous := q.file
+	q.file = file
+	q.segments = append(q.segments, s)
+
+	// The previous segment was flushed, only its descriptor is released
+	return previous.Close()
+}
+
+// removeAcked removes the oldest segments which have all jobs acknowledged.
+// Segments are removed in order, as a segment can hold the acks of the jobs in the segments before it.
+func (q *Queue) removeAcked() error {
+	for len(q.segments) > 1 && q.segments[0].live == 0 {
+		if err := os.Remove(q.path(q.segments[0].index)); err != nil {
+			return err
+		}
+		q.segments = q.segments[1:]
+	}
+
+	return nil
+}
+
+func (q *Queue) active() *segment {
+	return q.segments[len(q.segments)-1]
+}
+
+func (q *Queue) path(index int) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%08d%s", index, segmentSuffix))
+}
+
+// syncPeriodically flushes the writes at every interval.
+func (q *Queue) syncPeriodically(interval time.Duration) {
+	defer close(q.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.lock.Lock()
+			if q.dirty {
+				q.flush() // nolint:errcheck
+			}
+			q.lock.Unlock()
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+// replay reads all segments and restores the jobs which were not acknowledged.
+func (q *Queue) replay() error {
+	indexes, err := q.segmentIndexes()
+	if err != nil {
+		return err
+	}
+
+	live := make(map[uint64]*record)
+	segments := make(map[uint64]*segment)
+
+	for i, index := range indexes {
+		s := &segment{index: index}
+		q.segments = append(q.segments, s)
+
+		err := q.readSegment(s, i == len(indexes)-1, func(rec *record) {
+			if rec.Seq > q.seq {
+				q.seq = rec.Seq
+			}
+
+			switch rec.Type {
+			case pushRecord:
+				// A push which is seen again was rewritten by compaction
+				live[rec.Seq] = rec
+				segments[rec.Seq] = s
+			case ackRecord:
+				delete(live, rec.Seq)
+				delete(segments, rec.Seq)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for seq, rec := range live {
+		j, err := q.registry.Unmarshal(rec.Job)
+		if err != nil {
+			return fmt.Errorf("cannot restore job of record %d: %w", seq, err)
+		}
+
+		frame, err := encode(*rec)
+		if err != nil {
+			return err
+		}
+
+		s := segments[seq]
+		s.live++
+
+		heap.Push(&q.pending, &entry{
+			QueuedJob: runner.QueuedJob{Job: j, Priority: rec.Priority, EnqueuedAt: rec.EnqueuedAt, Key: rec.Key},
+			seq:       seq,
+			segment:   s,
+			frame:     frame,
+		})
+		q.counts[rec.Priority]++
+	}
+
+	return nil
+}
+
+// readSegment calls given function for every record of a segment.
+// An incomplete or corrupted record at the end of the last segment was being written when the process stopped,
+// so it is truncated. In any other segment it is an error.
+func (q *Queue) readSegment(s *segment, last bool, f func(*record)) error {
+	path := q.path(s.index)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	for offset < int64(len(data)) {
+		rec, n, err := decode(data[offset:])
+		if err != nil {
+			if !last {
+				return fmt.Errorf("segment %s at offset %d: %w", path, offset, err)
+			}
+			if err := os.Truncate(path, offset); err != nil {
+				return err
+			}
+			break
+		}
+
+		f(rec)
+		offset += int64(n)
+	}
+
+	s.size = offset
+
+	return nil
+}
+
+func (q *Queue) segmentIndexes() ([]int, error) {
+	files, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []int
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+
+		index, err := strconv.Atoi(strings.TrimSuffix(name, segmentSuffix))
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	return indexes, nil
+}
+
+// Open opens the log in given directory, replaying the jobs which were not acknowledged.
+func Open(c Config) (*Queue, error) {
+	if c.Registry == nil {
+		return nil, errors.New("nil registry passed to queue")
+	}
+	if c.SegmentSize <= 0 {
+		c.SegmentSize = segmentSize
+	}
+	if c.SyncInterval <= 0 {
+		c.SyncInterval = syncInterval
+	}
+
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	q := &Queue{
+		dir:      c.Dir,
+		registry: c.Registry,
+		maxSize:  c.SegmentSize,
+		sync:     c.Sync,
+		inflight: make(map[*job.Job][]*entry),
+		counts:   make(map[runner.Priority]int),
+	}
+
+	if err := q.replay(); err != nil {
+		return nil, err
+	}
+
+	if len(q.segments) == 0 {
+		q.segments = append(q.segments, &segment{index: 1})
+	}
+
+	file, err := os.OpenFile(q.path(q.active().index), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	q.file = file
+
+	if err := q.removeAcked(); err != nil {
+		file.Close() // nolint:errcheck
+		return nil, err
+	}
+
+	if q.sync == SyncInterval {
+		q.stop = make(chan struct{})
+		q.stopped = make(chan struct{})
+		go q.syncPeriodically(c.SyncInterval)
+	}
+
+	return q, nil
+}
+
+// encode returns the frame of a record: payload length, payload checksum and payload.
+func encode(rec record) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:], crc32.ChecksumIEEE(payload))
+	copy(frame[headerSize:], payload)
+
+	return frame, nil
+}
+
+// decode reads a record from the beginning of given data and returns the size of its frame.
+func decode(data []byte) (*record, int, error) {
+	if len(data) < headerSize {
+		return nil, 0, errors.New("incomplete frame header")
+	}
+
+	size := int(binary.BigEndian.Uint32(data))
+	if len(data)-headerSize < size {
+		return nil, 0, errors.New("incomplete frame")
+	}
+
+	payload := data[headerSize : headerSize+size]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(data[4:]) {
+		return nil, 0, errors.New("checksum mismatch")
+	}
+
+	var rec record
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return nil, 0, err
+	}
+
+	return &rec, headerSize + size, nil
+}
+
+// entries implements heap.Interface.
+type entries []*entry
+
+func (e entries) Len() int {
+	return len(e)
+}
+
+func (e entries) Less(i, j int) bool {
+	if e[i].Priority != e[j].Priority {
+		return e[i].Priority > e[j].Priority
+	}
+	return e[i].seq < e[j].seq
+}
+
+func (e entries) Swap(i, j int) {
+	e[i], e[j] = e[j], e[i]
+}
+
+func (e *entries) Push(x interface{}) {
+	*e = append(*e, x.(*entry)) // nolint:errcheck
+}
+
+func (e *entries) Pop() interface{} {
+	old := *e
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*e = old[:n-1]
+	return x
+}
@@ -0,0 +1,237 @@
+package walqueue_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/andreiavrammsd/workexec/walqueue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue_Replay(t *testing.T) {
+	dir := t.TempDir()
+	registry := newRegistry(t, nil)
+
+	q := open(t, walqueue.Config{Dir: dir, Registry: registry})
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	acked := push(t, q, "acked", runner.PriorityHigh, now)
+	push(t, q, "low", runner.PriorityLow, now)
+	popped := push(t, q, "popped", runner.PriorityNormal, now)
+	push(t, q, "normal", runner.PriorityNormal, now)
+
+	qj, ok := q.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, acked.ID(), qj.Job.ID())
+	assert.NoError(t, q.Ack(qj))
+
+	qj, ok = q.Pop()
+	assert.True(t, ok)
+	assert.Equal(t, popped.ID(), qj.Job.ID())
+	assert.NoError(t, q.Close())
+
+	assert.Error(t, q.Push(runner.QueuedJob{Job: acked}))
+
+	q = open(t, walqueue.Config{Dir: dir, Registry: registry})
+	defer q.Close()
+
+	// The popped job was not acknowledged, so it is replayed
+	assert.Equal(t, 3, q.Len())
+	assert.Equal(t, map[runner.Priority]int{runner.PriorityNormal: 2, runner.PriorityLow: 1}, q.Counts())
+
+	for _, expected := range []string{"popped", "normal", "low"} {
+		qj, ok := q.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, job.ID(expected), qj.Job.ID())
+		assert.Equal(t, &echoTask{Value: expected}, qj.Job.Task())
+		assert.True(t, now.Equal(qj.EnqueuedAt))
+	}
+
+	_, ok = q.Pop()
+	assert.False(t, ok)
+}
+
+func TestQueue_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	q := open(t, walqueue.Config{Dir: dir, Registry: newRegistry(t, nil), SegmentSize: 1, Sync: walqueue.SyncNever})
+	defer q.Close()
+
+	for _, id := range []string{"a", "b", "c"} {
+		push(t, q, id, runner.PriorityNormal, time.Now())
+	}
+	// Every record fills a segment
+	assert.Equal(t, 4, segments(t, dir))
+
+	for i := 0; i < 3; i++ {
+		qj, ok := q.Pop()
+		assert.True(t, ok)
+		assert.NoError(t, q.Ack(qj))
+	}
+	assert.Equal(t, 1, segments(t, dir))
+}
+
+func TestQueue_Compact(t *testing.T) {
+	dir := t.TempDir()
+	registry := newRegistry(t, nil)
+	q := open(t, walqueue.Config{
+		Dir:          dir,
+		Registry:     registry,
+		Sync:         walqueue.SyncInterval,
+		SyncInterval: time.Millisecond,
+	})
+
+	for _, id := range []string{"a", "b", "c", "d"} {
+		push(t, q, id, runner.PriorityNormal, time.Now())
+	}
+
+	a, _ := q.Pop()
+	b, _ := q.Pop()
+	assert.NoError(t, q.Ack(a))
+
+	assert.NoError(t, q.Compact())
+	assert.Equal(t, 1, segments(t, dir))
+	assert.NoError(t, q.Ack(b))
+
+	c, _ := q.Pop()
+	assert.NoError(t, q.Close())
+	assert.Error(t, q.Compact())
+
+	q = open(t, walqueue.Config{Dir: dir, Registry: registry})
+	defer q.Close()
+
+	assert.Equal(t, 2, q.Len())
+	qj, _ := q.Pop()
+	assert.Equal(t, c.Job.ID(), qj.Job.ID())
+	qj, _ = q.Pop()
+	assert.Equal(t, job.ID("d"), qj.Job.ID())
+}
+
+func TestQueue_TornTail(t *testing.T) {
+	dir := t.TempDir()
+	registry := newRegistry(t, nil)
+
+	q := open(t, walqueue.Config{Dir: dir, Registry: registry})
+	push(t, q, "a", runner.PriorityNormal, time.Now())
+	assert.NoError(t, q.Close())
+
+	// A record which was being written when the process stopped
+	path := filepath.Join(dir, "00000001.wal")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	assert.NoError(t, err)
+	_, err = file.Write([]byte{0, 0, 1, 0, 1, 2, 3})
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	q = open(t, walqueue.Config{Dir: dir, Registry: registry})
+	assert.Equal(t, 1, q.Len())
+	push(t, q, "b", runner.PriorityNormal, time.Now())
+	assert.NoError(t, q.Close())
+
+	q = open(t, walqueue.Config{Dir: dir, Registry: registry})
+	defer q.Close()
+	assert.Equal(t, 2, q.Len())
+}
+
+func TestOpen_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := walqueue.Open(walqueue.Config{Dir: dir})
+	assert.Error(t, err)
+
+	q := open(t, walqueue.Config{Dir: dir, Registry: newRegistry(t, nil)})
+	push(t, q, "a", runner.PriorityNormal, time.Now())
+	assert.NoError(t, q.Close())
+
+	// The task of the persisted job is unknown
+	_, err = walqueue.Open(walqueue.Config{Dir: dir, Registry: job.NewRegistry()})
+	assert.Error(t, err)
+}
+
+func TestQueue_Runner(t *testing.T) {
+	dir := t.TempDir()
+	results := make(chan string, 3)
+	registry := newRegistry(t, results)
+
+	q := open(t, walqueue.Config{Dir: dir, Registry: registry})
+	for _, id := range []string{"a", "b"} {
+		push(t, q, id, runner.PriorityNormal, time.Now())
+	}
+	assert.NoError(t, q.Close())
+
+	// After a restart
+	q = open(t, walqueue.Config{Dir: dir, Registry: registry})
+	defer q.Close()
+
+	r := runner.New(runner.Config{Concurrency: 1, Queue: q})
+	r.Start()
+
+	j, err := job.New(&echoTask{Value: "c", results: results})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Enqueue(j))
+
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, []string{<-results, <-results, <-results})
+
+	neverRan, err := r.Shutdown(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, neverRan)
+	assert.Equal(t, 0, q.Len())
+	assert.Equal(t, 1, segments(t, dir))
+}
+
+type echoTask struct {
+	Value   string `json:"value"`
+	results chan string
+}
+
+func (t *echoTask) Run(*job.Job) (interface{}, error) {
+	if t.results != nil {
+		t.results <- t.Value
+	}
+	return t.Value, nil
+}
+
+func newRegistry(t *testing.T, results chan string) *job.Registry {
+	t.Helper()
+
+	registry := job.NewRegistry()
+	assert.NoError(t, registry.Register("echo", func() interface{} {
+		return &echoTask{results: results}
+	}))
+
+	return registry
+}
+
+func open(t *testing.T, c walqueue.Config) *walqueue.Queue {
+	t.Helper()
+
+	q, err := walqueue.Open(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return q
+}
+
+func push(t *testing.T, q *walqueue.Queue, id string, priority runner.Priority, at time.Time) *job.Job {
+	t.Helper()
+
+	j, err := job.New(&echoTask{Value: id}, job.WithID(job.ID(id)))
+	assert.NoError(t, err)
+	assert.NoError(t, q.Push(runner.QueuedJob{Job: j, Priority: priority, EnqueuedAt: at}))
+
+	return j
+}
+
+func segments(t *testing.T, dir string) int {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	assert.NoError(t, err)
+
+	return len(files)
+}
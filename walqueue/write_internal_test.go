@@ -0,0 +1,180 @@
+package walqueue
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue_PartialWrite(t *testing.T) {
+	dir := t.TempDir()
+	registry := newTestRegistry(t)
+
+	q, err := Open(Config{Dir: dir, Registry: registry})
+	assert.NoError(t, err)
+	assert.NoError(t, q.Push(queuedJob(t, "a")))
+	size := q.active().size
+
+	file := &faultyFile{logFile: q.file, failWrite: true}
+	q.file = file
+
+	assert.Error(t, q.Push(queuedJob(t, "b")))
+	assert.Equal(t, size, q.active().size)
+	assert.Equal(t, 1, q.Len())
+
+	// The partial frame was removed, so the log is usable
+	file.failWrite = false
+	assert.NoError(t, q.Push(queuedJob(t, "c")))
+	assert.NoError(t, q.Close())
+
+	q, err = Open(Config{Dir: dir, Registry: registry})
+	assert.NoError(t, err)
+	defer q.Close()
+
+	assert.Equal(t, 2, q.Len())
+	qj, _ := q.Pop()
+	assert.Equal(t, job.ID("a"), qj.Job.ID())
+	qj, _ = q.Pop()
+	assert.Equal(t, job.ID("c"), qj.Job.ID())
+}
+
+func TestQueue_FailedRollback(t *testing.T) {
+	dir := t.TempDir()
+	registry := newTestRegistry(t)
+
+	q, err := Open(Config{Dir: dir, Registry: registry})
+	assert.NoError(t, err)
+	defer q.Close()
+
+	file := &faultyFile{logFile: q.file, failWrite: true, failTruncate: true}
+	q.file = file
+
+	assert.Error(t, q.Push(queuedJob(t, "a")))
+
+	// The log holds a partial frame, nothing can be written after it
+	file.failWrite = false
+	file.failTruncate = false
+	assert.ErrorContains(t, q.Push(queuedJob(t, "b")), "queue failed")
+	assert.ErrorContains(t, q.Compact(), "queue failed")
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestQueue_FailedSync(t *testing.T) {
+	dir := t.TempDir()
+	registry := newTestRegistry(t)
+
+	q, err := Open(Config{Dir: dir, Registry: registry})
+	assert.NoError(t, err)
+	assert.NoError(t, q.Push(queuedJob(t, "a")))
+	size := q.active().size
+
+	file := &faultyFile{logFile: q.file, failSync: true}
+	q.file = file
+
+	// The frame was written, but the push failed, so it must not be replayed
+	assert.Error(t, q.Push(queuedJob(t, "b")))
+	assert.Equal(t, size, q.active().size)
+	assert.Equal(t, uint64(1), q.seq)
+
+	file.failSync = false
+	assert.NoError(t, q.Push(queuedJob(t, "c")))
+	assert.NoError(t, q.Close())
+
+	q, err = Open(Config{Dir: dir, Registry: registry})
+	assert.NoError(t, err)
+	defer q.Close()
+
+	assert.Equal(t, 2, q.Len())
+	qj, _ := q.Pop()
+	assert.Equal(t, job.ID("a"), qj.Job.ID())
+	qj, _ = q.Pop()
+	assert.Equal(t, job.ID("c"), qj.Job.ID())
+}
+
+func TestQueue_FailedRotation(t *testing.T) {
+	dir := t.TempDir()
+	registry := newTestRegistry(t)
+
+	q, err := Open(Config{Dir: dir, Registry: registry, SegmentSize: 1})
+	assert.NoError(t, err)
+	defer q.Close()
+
+	// The next segment cannot be created
+	next := q.path(q.active().index + 1)
+	assert.NoError(t, os.Mkdir(next, 0o700))
+
+	assert.NoError(t, q.Push(queuedJob(t, "a")))
+	assert.Equal(t, 1, q.Len())
+	assert.Len(t, q.segments, 1)
+
+	// The rotation is done by the next write
+	assert.NoError(t, os.Remove(next))
+	assert.NoError(t, q.Push(queuedJob(t, "b")))
+	assert.Equal(t, 2, q.Len())
+	assert.Len(t, q.segments, 2)
+}
+
+// faultyFile writes half of the data, syncs and truncates, failing when told to.
+type faultyFile struct {
+	logFile
+	failWrite    bool
+	failTruncate bool
+	failSync     bool
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if !f.failWrite {
+		return f.logFile.Write(p)
+	}
+
+	n, err := f.logFile.Write(p[:len(p)/2])
+	if err != nil {
+		return n, err
+	}
+	return n, errors.New("no space left")
+}
+
+func (f *faultyFile) Sync() error {
+	if f.failSync {
+		return errors.New("cannot sync")
+	}
+	return f.logFile.Sync()
+}
+
+func (f *faultyFile) Truncate(size int64) error {
+	if f.failTruncate {
+		return errors.New("cannot truncate")
+	}
+	return f.logFile.Truncate(size)
+}
+
+type testTask struct{}
+
+func (testTask) Run(*job.Job) (interface{}, error) {
+	return nil, nil
+}
+
+func newTestRegistry(t *testing.T) *job.Registry {
+	t.Helper()
+
+	registry := job.NewRegistry()
+	assert.NoError(t, registry.Register("test", func() interface{} {
+		return &testTask{}
+	}))
+
+	return registry
+}
+
+func queuedJob(t *testing.T, id string) runner.QueuedJob {
+	t.Helper()
+
+	j, err := job.New(&testTask{}, job.WithID(job.ID(id)))
+	assert.NoError(t, err)
+
+	return runner.QueuedJob{Job: j, Priority: runner.PriorityNormal, EnqueuedAt: time.Now()}
+}
@@ -0,0 +1,481 @@
+// Package workflow runs jobs which depend on each other, as a directed acyclic graph, on a runner.
+// A node is enqueued when all the nodes it depends on succeeded, and it can use their results.
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+)
+
+// ErrSkipped is the error of a node which did not run because of a failure.
+var ErrSkipped = errors.New("skipped")
+
+// Policy tells what happens to the other nodes when a node fails or is canceled.
+type Policy int
+
+const (
+	// FailFast cancels the running nodes and skips all the nodes which did not start.
+	FailFast Policy = iota
+	// ContinueOnFailure skips only the nodes which depend on the failed node. Independent branches go on.
+	ContinueOnFailure
+)
+
+// Outcome of a node.
+type Outcome int
+
+const (
+	// Succeeded node.
+	Succeeded Outcome = iota
+	// Failed node, or a node which could not be enqueued.
+	Failed
+	// Canceled node.
+	Canceled
+	// Skipped node, which did not run because of a failure.
+	Skipped
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Succeeded:
+		return "succeeded"
+	case Failed:
+		return "failed"
+	case Canceled:
+		return "canceled"
+	case Skipped:
+		return "skipped"
+	default:
+		return fmt.Sprintf("Outcome(%d)", int(o))
+	}
+}
+
+// MissingDependencyError is returned for a node which depends on a node that was not added.
+type MissingDependencyError struct {
+	Node       string
+	Dependency string
+}
+
+func (e *MissingDependencyError) Error() string {
+	return fmt.Sprintf("node %q depends on missing node %q", e.Node, e.Dependency)
+}
+
+// CycleError is returned for nodes which depend on each other.
+type CycleError struct {
+	// Path starts and ends with the same node.
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// Results are the results of the nodes a node depends on, by name.
+type Results map[string]interface{}
+
+// Builder creates the job of a node from the results of the nodes it depends on.
+type Builder func(Results) (*job.Job, error)
+
+// NodeResult is the outcome of a node.
+type NodeResult struct {
+	Name    string
+	JobID   job.ID
+	Outcome Outcome
+	Result  interface{}
+	Err     error
+}
+
+// Config allows setup of a workflow.
+type Config struct {
+	// Policy on failure. Defaults to FailFast.
+	Policy Policy
+
+	// Priority of the jobs in the runner.
+	Priority runner.Priority
+}
+
+// node is a job of the workflow.
+type node struct {
+	name      string
+	build     Builder
+	dependsOn []string
+}
+
+// Workflow is a graph of jobs.
+type Workflow struct {
+	policy   Policy
+	priority runner.Priority
+	nodes    map[string]*node
+	// order is the order the nodes were added in
+	order []string
+}
+
+// Add adds a node running given job. A job can run once, so a workflow with nodes added by Add can run once.
+func (w *Workflow) Add(name string, j *job.Job, dependsOn ...string) error {
+	if j == nil {
+		return errors.New("nil job passed to workflow")
+	}
+
+	return w.AddFunc(name, func(Results) (*job.Job, error) {
+		return j, nil
+	}, dependsOn...)
+}
+
+// AddFunc adds a node running the job created by given builder when the node is ready.
+func (w *Workflow) AddFunc(name string, build Builder, dependsOn ...string) error {
+	if name == "" {
+		return errors.New("empty node name")
+	}
+	if build == nil {
+		return errors.New("nil builder passed to workflow")
+	}
+	if _, ok := w.nodes[name]; ok {
+		return fmt.Errorf("node %q already added", name)
+	}
+
+	w.nodes[name] = &node{name: name, build: build, dependsOn: dependsOn}
+	w.order = append(w.order, name)
+
+	return nil
+}
+
+// Validate checks all dependencies were added and there are no cycles.
+func (w *Workflow) Validate() error {
+	for _, name := range w.order {
+		for _, dependency := range w.nodes[name].dependsOn {
+			if _, ok := w.nodes[dependency]; !ok {
+				return &MissingDependencyError{Node: name, Dependency: dependency}
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(w.nodes))
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					cycle := append(append([]string{}, path[i:]...), name)
+					return &CycleError{Path: cycle}
+				}
+			}
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dependency := range w.nodes[name].dependsOn {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, name := range w.order {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run validates the workflow and enqueues the nodes without dependencies to given runner.
+// The other nodes are enqueued when their dependencies succeeded.
+func (w *Workflow) Run(r *runner.Runner) (*Future, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	e := &execution{
+		workflow:  w,
+		runner:    r,
+		remaining: make(map[string]int, len(w.nodes)),
+		children:  make(map[string][]string, len(w.nodes)),
+		running:   make(map[string]job.ID),
+		future:    &Future{done: make(chan struct{}), results: make(map[string]NodeResult, len(w.nodes))},
+	}
+	e.future.execution = e
+
+	var ready []string
+	for _, name := range w.order {
+		n := w.nodes[name]
+		e.remaining[name] = len(n.dependsOn)
+		for _, dependency := range n.dependsOn {
+			e.children[dependency] = append(e.children[dependency], name)
+		}
+		if len(n.dependsOn) == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	if len(w.nodes) == 0 {
+		close(e.future.done)
+	}
+
+	for _, name := range ready {
+		e.start(name)
+	}
+
+	return e.future, nil
+}
+
+// New creates an empty workflow.
+func New(c Config) *Workflow {
+	return &Workflow{
+		policy:   c.Policy,
+		priority: c.Priority,
+		nodes:    make(map[string]*node),
+	}
+}
+
+// execution is a run of a workflow.
+type execution struct {
+	lock      sync.Mutex
+	workflow  *Workflow
+	runner    *runner.Runner
+	remaining map[string]int
+	children  map[string][]string
+	running   map[string]job.ID
+	aborted   bool
+	future    *Future
+}
+
+// start builds and enqueues the job of a node.
+func (e *execution) start(name string) {
+	n := e.workflow.nodes[name]
+
+	e.lock.Lock()
+	if e.aborted {
+		e.skip(name)
+		e.lock.Unlock()
+		return
+	}
+
+	parents := make(Results, len(n.dependsOn))
+	for _, dependency := range n.dependsOn {
+		parents[dependency] = e.future.results[dependency].Result
+	}
+	e.lock.Unlock()
+
+	j, err := n.build(parents)
+	if err == nil && j == nil {
+		err = errors.New("nil job built")
+	}
+	if err != nil {
+		e.lock.Lock()
+		e.fail(NodeResult{Name: name, Outcome: Failed, Err: err})
+		e.lock.Unlock()
+		return
+	}
+
+	e.lock.Lock()
+	if e.aborted {
+		e.skip(name)
+		e.lock.Unlock()
+		return
+	}
+	e.running[name] = j.ID()
+	e.lock.Unlock()
+
+	opts := runner.EnqueueOptions{
+		Priority: e.workflow.priority,
+		OnDone: func(info runner.JobInfo) {
+			e.finished(name, info)
+		},
+	}
+	if err := e.runner.EnqueueWithOptions(opts, j); err != nil {
+		e.lock.Lock()
+		delete(e.running, name)
+		e.fail(NodeResult{Name: name, JobID: j.ID(), Outcome: Failed, Err: err})
+		e.lock.Unlock()
+	}
+}
+
+// finished records the outcome of a node and starts the nodes which are ready.
+func (e *execution) finished(name string, info runner.JobInfo) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	delete(e.running, name)
+
+	result := NodeResult{Name: name, JobID: info.ID, Result: info.Result, Err: info.Err}
+	switch info.State {
+	case runner.StateSucceeded:
+		result.Outcome = Succeeded
+	case runner.StateCanceled:
+		result.Outcome = Canceled
+	default:
+		result.Outcome = Failed
+	}
+
+	if result.Outcome != Succeeded {
+		e.fail(result)
+		return
+	}
+
+	e.record(result)
+
+	var ready []string
+	for _, child := range e.children[name] {
+		e.remaining[child]--
+		if _, done := e.future.results[child]; !done && e.remaining[child] == 0 {
+			ready = append(ready, child)
+		}
+	}
+
+	// Not enqueuing on the routine of the worker, which could be needed to free the queue
+	for _, child := range ready {
+		go e.start(child)
+	}
+}
+
+// fail records a node which did not succeed and applies the policy. It must be called with the lock held.
+func (e *execution) fail(result NodeResult) {
+	if e.future.err == nil {
+		e.future.err = fmt.Errorf("node %q %s: %w", result.Name, result.Outcome, result.Err)
+	}
+	e.record(result)
+
+	if e.workflow.policy == FailFast {
+		e.abort()
+		return
+	}
+
+	e.skipChildren(result.Name)
+}
+
+// abort cancels the running nodes and skips the others. It must be called with the lock held.
+func (e *execution) abort() {
+	if e.aborted {
+		return
+	}
+	e.aborted = true
+
+	for _, id := range e.running {
+		e.runner.Cancel(id)
+	}
+
+	for _, name := range e.workflow.order {
+		if _, running := e.running[name]; running {
+			continue
+		}
+		if _, done := e.future.results[name]; !done && e.remaining[name] > 0 {
+			e.skip(name)
+		}
+	}
+}
+
+// skipChildren skips the nodes which depend on given node, directly or not. It must be called with the lock held.
+func (e *execution) skipChildren(name string) {
+	for _, child := range e.children[name] {
+		if _, done := e.future.results[child]; done {
+			continue
+		}
+		e.skip(child)
+		e.skipChildren(child)
+	}
+}
+
+// skip records a node which did not run. It must be called with the lock held.
+func (e *execution) skip(name string) {
+	if _, done := e.future.results[name]; done {
+		return
+	}
+	e.record(NodeResult{Name: name, Outcome: Skipped, Err: ErrSkipped})
+}
+
+// record sets the result of a node. It must be called with the lock held.
+func (e *execution) record(result NodeResult) {
+	e.future.results[result.Name] = result
+
+	if len(e.future.results) == len(e.workflow.nodes) {
+		close(e.future.done)
+	}
+}
+
+// Future is the outcome of a workflow run.
+type Future struct {
+	execution *execution
+	done      chan struct{}
+	results   map[string]NodeResult
+	err       error
+}
+
+// Wait blocks until all nodes are done.
+func (f *Future) Wait() {
+	<-f.done
+}
+
+// WaitContext blocks until all nodes are done or given context is done.
+// It returns the error of the context if it is done first.
+func (f *Future) WaitContext(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel which is closed when all nodes are done.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Cancel cancels the running nodes and skips the others.
+func (f *Future) Cancel() {
+	f.execution.lock.Lock()
+	defer f.execution.lock.Unlock()
+
+	f.execution.abort()
+}
+
+// Error returns the error of the first node which did not succeed, nil if all succeeded.
+func (f *Future) Error() error {
+	f.execution.lock.Lock()
+	defer f.execution.lock.Unlock()
+
+	return f.err
+}
+
+// Result returns the outcome of a node, if it is done.
+func (f *Future) Result(name string) (NodeResult, bool) {
+	f.execution.lock.Lock()
+	defer f.execution.lock.Unlock()
+
+	result, ok := f.results[name]
+	return result, ok
+}
+
+// Results returns the outcome of the nodes which are done, by name.
+func (f *Future) Results() map[string]NodeResult {
+	f.execution.lock.Lock()
+	defer f.execution.lock.Unlock()
+
+	results := make(map[string]NodeResult, len(f.results))
+	for name, result := range f.results {
+		results[name] = result
+	}
+	return results
+}
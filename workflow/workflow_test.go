@@ -0,0 +1,277 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andreiavrammsd/workexec/job"
+	"github.com/andreiavrammsd/workexec/runner"
+	"github.com/andreiavrammsd/workexec/workflow"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflow_Validate(t *testing.T) {
+	w := workflow.New(workflow.Config{})
+	assert.NoError(t, w.Add("a", newJob(t, &valueTask{})))
+	assert.NoError(t, w.Add("b", newJob(t, &valueTask{}), "a", "c"))
+
+	assert.Error(t, w.Add("a", newJob(t, &valueTask{})))
+	assert.Error(t, w.Add("", newJob(t, &valueTask{})))
+	assert.Error(t, w.Add("nil", nil))
+	assert.Error(t, w.AddFunc("nil", nil))
+
+	var missing *workflow.MissingDependencyError
+	assert.True(t, errors.As(w.Validate(), &missing))
+	assert.Equal(t, "b", missing.Node)
+	assert.Equal(t, "c", missing.Dependency)
+
+	assert.NoError(t, w.Add("c", newJob(t, &valueTask{}), "d"))
+	assert.NoError(t, w.Add("d", newJob(t, &valueTask{}), "b"))
+
+	var cycle *workflow.CycleError
+	assert.True(t, errors.As(w.Validate(), &cycle))
+	assert.Equal(t, []string{"b", "c", "d", "b"}, cycle.Path)
+	assert.Equal(t, "dependency cycle: b -> c -> d -> b", cycle.Error())
+
+	_, err := w.Run(runner.New(runner.Config{}))
+	assert.Error(t, err)
+}
+
+func TestWorkflow_Run(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 4})
+	r.Start()
+	defer r.Stop()
+
+	w := workflow.New(workflow.Config{})
+	assert.NoError(t, w.Add("a", newJob(t, &valueTask{value: 1})))
+	assert.NoError(t, w.Add("b", newJob(t, &valueTask{value: 2})))
+	assert.NoError(t, w.AddFunc("sum", func(parents workflow.Results) (*job.Job, error) {
+		return job.New(&valueTask{value: parents["a"].(int) + parents["b"].(int)})
+	}, "a", "b"))
+	assert.NoError(t, w.AddFunc("double", func(parents workflow.Results) (*job.Job, error) {
+		return job.New(&valueTask{value: parents["sum"].(int) * 2})
+	}, "sum"))
+
+	future, err := w.Run(r)
+	assert.NoError(t, err)
+	assert.NoError(t, future.WaitContext(timeout(t)))
+	assert.NoError(t, future.Error())
+
+	results := future.Results()
+	assert.Len(t, results, 4)
+	assert.Equal(t, 3, results["sum"].Result)
+	assert.Equal(t, 6, results["double"].Result)
+	assert.Equal(t, workflow.Succeeded, results["double"].Outcome)
+	assert.NotEmpty(t, results["double"].JobID)
+
+	empty, err := workflow.New(workflow.Config{}).Run(r)
+	assert.NoError(t, err)
+	empty.Wait()
+}
+
+func TestWorkflow_FailFast(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 2})
+	r.Start()
+	defer r.Stop()
+
+	blocking := &blockingTask{started: make(chan struct{})}
+	failing := &valueTask{err: errors.New("failed"), wait: blocking.started}
+
+	w := workflow.New(workflow.Config{Policy: workflow.FailFast})
+	assert.NoError(t, w.Add("blocking", newContextJob(t, blocking)))
+	assert.NoError(t, w.Add("failing", newJob(t, failing)))
+	assert.NoError(t, w.Add("after blocking", newJob(t, &valueTask{}), "blocking"))
+	assert.NoError(t, w.Add("after failing", newJob(t, &valueTask{}), "failing"))
+
+	future, err := w.Run(r)
+	assert.NoError(t, err)
+	assert.NoError(t, future.WaitContext(timeout(t)))
+
+	assert.EqualError(t, future.Error(), `node "failing" failed: failed`)
+
+	results := future.Results()
+	assert.Equal(t, workflow.Failed, results["failing"].Outcome)
+	assert.Equal(t, workflow.Canceled, results["blocking"].Outcome)
+	assert.ErrorIs(t, results["blocking"].Err, runner.ErrCanceled)
+	assert.Equal(t, workflow.Skipped, results["after blocking"].Outcome)
+	assert.Equal(t, workflow.Skipped, results["after failing"].Outcome)
+	assert.ErrorIs(t, results["after failing"].Err, workflow.ErrSkipped)
+}
+
+func TestWorkflow_ContinueOnFailure(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 2})
+	r.Start()
+	defer r.Stop()
+
+	w := workflow.New(workflow.Config{Policy: workflow.ContinueOnFailure})
+	assert.NoError(t, w.Add("a", newJob(t, &valueTask{value: 1})))
+	assert.NoError(t, w.AddFunc("b", func(workflow.Results) (*job.Job, error) {
+		return nil, errors.New("cannot build")
+	}))
+	assert.NoError(t, w.Add("after a", newJob(t, &valueTask{value: 2}), "a"))
+	assert.NoError(t, w.Add("after b", newJob(t, &valueTask{}), "b"))
+	assert.NoError(t, w.Add("after both", newJob(t, &valueTask{}), "after a", "after b"))
+
+	future, err := w.Run(r)
+	assert.NoError(t, err)
+	assert.NoError(t, future.WaitContext(timeout(t)))
+
+	assert.EqualError(t, future.Error(), `node "b" failed: cannot build`)
+
+	outcomes := map[string]workflow.Outcome{}
+	for name, result := range future.Results() {
+		outcomes[name] = result.Outcome
+	}
+	assert.Equal(t, map[string]workflow.Outcome{
+		"a":          workflow.Succeeded,
+		"b":          workflow.Failed,
+		"after a":    workflow.Succeeded,
+		"after b":    workflow.Skipped,
+		"after both": workflow.Skipped,
+	}, outcomes)
+
+	result, ok := future.Result("after a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, result.Result)
+}
+
+func TestFuture_Cancel(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+	defer r.Stop()
+
+	blocking := &blockingTask{started: make(chan struct{})}
+
+	w := workflow.New(workflow.Config{})
+	assert.NoError(t, w.Add("blocking", newContextJob(t, blocking)))
+	assert.NoError(t, w.Add("next", newJob(t, &valueTask{}), "blocking"))
+
+	future, err := w.Run(r)
+	assert.NoError(t, err)
+
+	<-blocking.started
+	future.Cancel()
+	assert.NoError(t, future.WaitContext(timeout(t)))
+
+	result, _ := future.Result("blocking")
+	assert.Equal(t, workflow.Canceled, result.Outcome)
+	assert.Equal(t, "canceled", result.Outcome.String())
+	result, _ = future.Result("next")
+	assert.Equal(t, workflow.Skipped, result.Outcome)
+}
+
+func TestWorkflow_Shutdown(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+
+	first := &blockingTask{started: make(chan struct{})}
+	second := &blockingTask{started: make(chan struct{})}
+
+	w := workflow.New(workflow.Config{Policy: workflow.ContinueOnFailure})
+	assert.NoError(t, w.Add("first", newContextJob(t, first)))
+	assert.NoError(t, w.Add("second", newContextJob(t, second)))
+
+	future, err := w.Run(r)
+	assert.NoError(t, err)
+
+	// One node runs, the other one is queued
+	select {
+	case <-first.started:
+	case <-second.started:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	neverRan, err := r.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, neverRan, 1)
+
+	// The node dropped by the shutdown is done too
+	assert.NoError(t, future.WaitContext(timeout(t)))
+
+	for _, name := range []string{"first", "second"} {
+		result, _ := future.Result(name)
+		assert.Equal(t, workflow.Canceled, result.Outcome, name)
+	}
+}
+
+func TestWorkflow_Stop(t *testing.T) {
+	r := runner.New(runner.Config{Concurrency: 1})
+	r.Start()
+
+	first := &blockingTask{started: make(chan struct{})}
+	second := &blockingTask{started: make(chan struct{})}
+
+	w := workflow.New(workflow.Config{Policy: workflow.ContinueOnFailure})
+	assert.NoError(t, w.Add("first", newContextJob(t, first)))
+	assert.NoError(t, w.Add("second", newContextJob(t, second)))
+
+	future, err := w.Run(r)
+	assert.NoError(t, err)
+
+	// One node runs, the other one is queued
+	select {
+	case <-first.started:
+	case <-second.started:
+	}
+	r.Stop()
+
+	// The node still queued at stop is done too
+	assert.NoError(t, future.WaitContext(timeout(t)))
+
+	for _, name := range []string{"first", "second"} {
+		result, _ := future.Result(name)
+		assert.Equal(t, workflow.Canceled, result.Outcome, name)
+	}
+}
+
+type valueTask struct {
+	value int
+	err   error
+	wait  chan struct{}
+}
+
+func (t *valueTask) Run(*job.Job) (interface{}, error) {
+	if t.wait != nil {
+		<-t.wait
+	}
+	return t.value, t.err
+}
+
+type blockingTask struct {
+	started chan struct{}
+}
+
+func (t *blockingTask) RunContext(ctx context.Context) (interface{}, error) {
+	close(t.started)
+	<-ctx.Done()
+	return nil, nil
+}
+
+func newJob(t *testing.T, task job.Task) *job.Job {
+	t.Helper()
+
+	j, err := job.New(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func newContextJob(t *testing.T, task job.ContextTask) *job.Job {
+	t.Helper()
+
+	j, err := job.NewContext(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func timeout(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	t.Cleanup(cancel)
+	return ctx
+}